@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local cache of downloaded workflows and actions.",
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached workflow and action.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := resolveCacheDir()
+		if err != nil {
+			return err
+		}
+		if err := github.ClearCache(dir); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Cache cleared: %s\n", dir)
+		return nil
+	},
+}
+
+// resolveCacheDir returns the --cache-dir flag value or the default cache directory.
+func resolveCacheDir() (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	return github.DefaultCacheDir()
+}
+
+// newDownloader creates the GitHub client, wrapped with the on-disk cache unless --no-cache is set.
+func newDownloader() (github.WorkflowDownloader, error) {
+	host := resolveGitHubHost()
+	// The token itself is never logged, only where it comes from.
+	accessToken, source, err := github.ResolveToken(token, tokenFile, host)
+	if err != nil {
+		return nil, err
+	}
+	if source == "" {
+		slog.Debug("No GitHub token found, using anonymous access", "host", host)
+	} else {
+		slog.Debug("Using GitHub token", "host", host, "source", source)
+	}
+
+	client := github.NewClientForHost(accessToken, host).
+		SetTimeout(requestTimeout).
+		SetMaxRetries(retries)
+	if noCache {
+		return client, nil
+	}
+	dir, err := resolveCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return github.NewCachedDownloader(client, dir, cacheTTL), nil
+}
@@ -1,56 +1,158 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/leocomelli/wk2mmd/internal/app"
+	"github.com/leocomelli/wk2mmd/internal/github"
 	"github.com/spf13/cobra"
 )
 
 var (
-	diagramType string
-	depth       int
-	token       string
-	logLevel    string
+	diagramType    string
+	depth          int
+	token          string
+	tokenFile      string
+	logLevel       string
+	outputFile     string
+	format         string
+	noCache        bool
+	cacheDir       string
+	cacheTTL       time.Duration
+	concurrency    int
+	githubHost     string
+	requestTimeout time.Duration
+	timeout        time.Duration
+	retries        int
+	strict         bool
+	view           string
+	detail         string
+	theme          string
+	legend         bool
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "wk2mmd <workflow-url>",
+	Use:   "wk2mmd <workflow-url|directory|owner/repo[@ref]>",
 	Short: "Generate a Mermaid diagram from a GitHub Actions workflow file.",
-	Args:  cobra.ExactArgs(1),
+	Long: `Generate a Mermaid diagram from a GitHub Actions workflow file.
+
+When a directory or an owner/repo[@ref] reference is given, every workflow in
+.github/workflows is analyzed and rendered in a combined diagram.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		workflowURL := args[0]
-		runner := app.NewWorkflowRunner(token)
-
-		slog.Debug("Running workflow analysis", "workflowURL", workflowURL, "depth", depth, "diagramType", diagramType)
-		output, err := runner.RunWorkflowAnalysis(workflowURL, depth, diagramType)
+		client, err := newDownloader()
 		if err != nil {
 			return err
 		}
+		runner := app.NewWorkflowRunnerWithClient(client).SetFormat(format).SetConcurrency(concurrency).SetStrict(strict).SetView(view).SetDetail(detail).
+			SetTheme(theme).SetLegend(legend)
+
+		ctx := cmd.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
 
-		fmt.Println(output)
+		var output string
+		if app.IsRepositorySource(workflowURL) {
+			slog.Debug("Running repository analysis", "source", workflowURL, "depth", depth, "diagramType", diagramType)
+			output, err = runner.RunRepositoryAnalysis(ctx, workflowURL, depth, diagramType)
+		} else {
+			slog.Debug("Running workflow analysis", "workflowURL", workflowURL, "depth", depth, "diagramType", diagramType)
+			output, err = runner.RunWorkflowAnalysis(ctx, workflowURL, depth, diagramType)
+		}
+		// In strict mode the diagram is still written when references are unresolved or cyclic, before failing.
+		var unresolvedErr *app.UnresolvedError
+		switch {
+		case errors.As(err, &unresolvedErr):
+			// The diagram was rendered: the usage is not relevant, and Execute reports the error.
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		case errors.Is(err, context.DeadlineExceeded):
+			return fmt.Errorf("analysis timed out after %s: %w", timeout, err)
+		case errors.Is(err, context.Canceled):
+			return fmt.Errorf("analysis interrupted: %w", err)
+		case err != nil:
+			return err
+		}
 
-		return nil
+		if outputFile == "" {
+			fmt.Println(output)
+		} else if writeErr := writeOutput(outputFile, output); writeErr != nil {
+			return writeErr
+		}
+		return err
 	},
 }
 
+// resolveGitHubHost returns the GitHub host from --github-host, then $GH_HOST, defaulting to github.com.
+func resolveGitHubHost() string {
+	if githubHost != "" {
+		return github.NormalizeHost(githubHost)
+	}
+	return github.NormalizeHost(os.Getenv("GH_HOST"))
+}
+
 // Execute runs the root command.
 func Execute() {
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "set log level: debug, info, warn, error")
 	rootCmd.Flags().StringVarP(&diagramType, "diagram-type", "t", "flowchart", "Mermaid diagram type: flowchart or sequence")
+	rootCmd.Flags().StringVarP(&format, "format", "f", "mermaid", "Output format: mermaid, dot, plantuml, d2, json or yaml")
+	rootCmd.Flags().StringVar(&view, "view", "tree", "Graph view: tree (references under each caller) or dag (one node per unique reference)")
+	rootCmd.Flags().StringVar(&detail, "detail", "jobs", "Detail level: jobs (steps using an action), steps (every step) or full (steps and their attributes)")
+	rootCmd.Flags().StringVar(&theme, "theme", "default", "Mermaid flowchart theme: default, dark or the path of a YAML theme file")
+	rootCmd.Flags().BoolVar(&legend, "legend", false, "Add a legend of the kinds of nodes to Mermaid flowcharts")
 	rootCmd.Flags().IntVarP(&depth, "depth", "d", 2, "Maximum depth for recursive 'uses' analysis")
-	rootCmd.Flags().StringVarP(&token, "token", "k", "", "GitHub token for accessing private repositories")
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", github.DefaultConcurrency, "Maximum number of workflows and actions fetched in parallel")
+	rootCmd.Flags().StringVar(&githubHost, "github-host", "", "GitHub Enterprise Server host name (default: $GH_HOST or github.com)")
+	rootCmd.Flags().StringVarP(&token, "token", "k", "", "GitHub token for accessing private repositories (default: $GITHUB_TOKEN, $GH_TOKEN or the gh CLI config)")
+	rootCmd.Flags().StringVar(&tokenFile, "token-file", "", "Read the GitHub token from a file")
+	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write the diagram to a file instead of stdout")
+	rootCmd.Flags().DurationVar(&timeout, "timeout", 0, "Maximum duration of the whole analysis (0 for none)")
+	rootCmd.Flags().DurationVar(&requestTimeout, "request-timeout", github.DefaultTimeout, "Timeout of each HTTP request to GitHub (0 for none)")
+	rootCmd.Flags().IntVar(&retries, "retries", github.DefaultMaxRetries, "Retries of failed or rate limited HTTP requests")
+	rootCmd.Flags().BoolVar(&strict, "strict", false, "Exit with an error if any 'uses' reference could not be resolved or forms a cycle")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Disable the local cache of downloaded workflows and actions")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", github.DefaultCacheTTL, "How long cached workflows and actions are reused")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Cache directory (default: <user cache dir>/wk2mmd)")
+
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
 
 	cobra.OnInitialize(setupLogger)
 
-	if err := rootCmd.Execute(); err != nil {
+	// Ctrl-C cancels the analysis, aborting the downloads in flight.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
+// writeOutput writes the diagram to the given file, creating parent directories as needed.
+func writeOutput(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	slog.Info("Diagram written", "path", path)
+	return nil
+}
+
 func setupLogger() {
 	var level slog.Level
 	switch logLevel {
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/leocomelli/wk2mmd/internal/app"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,3 +32,61 @@ func TestExecute_NoArgs(t *testing.T) {
 	err := cmd.Execute()
 	assert.Error(t, err)
 }
+
+func TestWriteOutput_CreatesParentDirs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs", "diagrams", "ci.mmd")
+	err := writeOutput(path, "flowchart TB")
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "flowchart TB\n", string(data))
+}
+
+func TestCacheClear(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	entry := filepath.Join(dir, "owner", "repo", strings.Repeat("0", 64))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(entry), 0o755))
+	assert.NoError(t, os.WriteFile(entry, []byte("jobs: {}"), 0o600))
+
+	cacheDir = dir
+	defer func() { cacheDir = "" }()
+	err := cacheClearCmd.RunE(cacheClearCmd, nil)
+	assert.NoError(t, err)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestResolveGitHubHost(t *testing.T) {
+	t.Setenv("GH_HOST", "")
+	assert.Equal(t, "github.com", resolveGitHubHost())
+
+	t.Setenv("GH_HOST", "ghes.example.com")
+	assert.Equal(t, "ghes.example.com", resolveGitHubHost())
+
+	githubHost = "https://other.example.com/"
+	defer func() { githubHost = "" }()
+	assert.Equal(t, "other.example.com", resolveGitHubHost())
+}
+
+func TestRootCmd_StrictSilencesUsage(t *testing.T) {
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, "ci.yml")
+	assert.NoError(t, os.WriteFile(workflow, []byte("jobs:\n  build:\n    steps:\n      - uses: ./missing\n"), 0o644))
+
+	strict, depth, noCache, outputFile = true, 2, true, filepath.Join(dir, "ci.mmd")
+	diagramType, format = "flowchart", "mermaid"
+	defer func() {
+		strict, depth, noCache, outputFile = false, 0, false, ""
+		diagramType, format = "", ""
+		rootCmd.SilenceUsage, rootCmd.SilenceErrors = false, false
+	}()
+
+	err := rootCmd.RunE(rootCmd, []string{workflow})
+	var unresolvedErr *app.UnresolvedError
+	assert.ErrorAs(t, err, &unresolvedErr)
+	assert.True(t, rootCmd.SilenceUsage)
+	assert.True(t, rootCmd.SilenceErrors)
+	_, statErr := os.Stat(outputFile)
+	assert.NoError(t, statErr, "the diagram is written before failing")
+}
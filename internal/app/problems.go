@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+)
+
+// UnresolvedError is returned in strict mode, together with the rendered diagram, when some references
+// could not be resolved or form cycles.
+type UnresolvedError struct {
+	Problems []github.Problem
+	Cycles   []github.Cycle
+}
+
+// Error implements the error interface, listing every unresolved reference and every cycle.
+func (e *UnresolvedError) Error() string {
+	var sb strings.Builder
+	if len(e.Problems) > 0 {
+		fmt.Fprintf(&sb, "%d reference(s) could not be resolved:", len(e.Problems))
+		for _, p := range e.Problems {
+			fmt.Fprintf(&sb, "\n  - %s: %s", p.Uses, p.Reason)
+		}
+	}
+	if len(e.Cycles) > 0 {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%d reference cycle(s):", len(e.Cycles))
+		for _, c := range e.Cycles {
+			fmt.Fprintf(&sb, "\n  - %s", c)
+		}
+	}
+	return sb.String()
+}
+
+// SetStrict makes the analyses fail with an *UnresolvedError when references cannot be resolved or
+// form cycles, and returns the runner for chaining.
+func (wr *WorkflowRunner) SetStrict(strict bool) *WorkflowRunner {
+	wr.strict = strict
+	return wr
+}
+
+// checkProblems reports the unresolved references and the reference cycles of the tree on stderr and,
+// in strict mode, returns them as an *UnresolvedError.
+func (wr *WorkflowRunner) checkProblems(tree *github.UsesNode) error {
+	problems, cycles := github.Problems(tree), github.Cycles(tree)
+	if len(problems) == 0 && len(cycles) == 0 {
+		return nil
+	}
+	if len(problems) > 0 {
+		slog.Warn("Some references could not be resolved", "count", len(problems))
+	}
+	for _, p := range problems {
+		slog.Warn("Unresolved reference", "uses", p.Uses, "reason", p.Reason, "node", p.NodeID, "error", p.Err)
+	}
+	for _, c := range cycles {
+		slog.Warn("Reference cycle", "cycle", c.String(), "node", c.NodeID)
+	}
+	if wr.strict {
+		return &UnresolvedError{Problems: problems, Cycles: cycles}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWorkflowAnalysis_Unresolved(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			if url == "workflow.yml" {
+				return []byte(`jobs: { call: { uses: "./.github/workflows/missing.yml" }, build: { steps: [ { uses: "./actions/broken" } ] } }`), nil
+			}
+			if url == "./actions/broken/action.yml" {
+				return []byte("invalid: [unclosed"), nil
+			}
+			return nil, errors.New("not found")
+		},
+	}
+
+	runner := NewWorkflowRunnerWithClient(client)
+	output, err := runner.RunWorkflowAnalysis(context.Background(), "workflow.yml", 2, "flowchart")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "unresolved: parse error")
+	assert.Contains(t, output, "classDef unresolved")
+
+	output, err = runner.SetStrict(true).RunWorkflowAnalysis(context.Background(), "workflow.yml", 2, "flowchart")
+	assert.NotEmpty(t, output, "the diagram is rendered in strict mode too")
+	var unresolvedErr *UnresolvedError
+	assert.ErrorAs(t, err, &unresolvedErr)
+	assert.Len(t, unresolvedErr.Problems, 2)
+	assert.Contains(t, err.Error(), "2 reference(s) could not be resolved")
+	assert.Contains(t, err.Error(), "./actions/broken: parse error")
+}
+
+func TestRunWorkflowAnalysis_StrictIgnoresDepthLimit(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			return []byte(`jobs: { call: { uses: "./.github/workflows/reusable.yml" } }`), nil
+		},
+	}
+	runner := NewWorkflowRunnerWithClient(client).SetStrict(true)
+	output, err := runner.RunWorkflowAnalysis(context.Background(), "workflow.yml", 1, "flowchart")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "unresolved: depth limit")
+}
+
+func TestRunWorkflowAnalysis_Cycle(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			switch url {
+			case "https://github.com/owner/repo/blob/v1/.github/workflows/ci.yml":
+				return []byte(`jobs: { call: { uses: "./.github/workflows/build.yml" } }`), nil
+			case "https://raw.githubusercontent.com/owner/repo/refs/tags/v1/.github/workflows/build.yml":
+				return []byte(`jobs: { deploy: { uses: "./.github/workflows/deploy.yml" } }`), nil
+			case "https://raw.githubusercontent.com/owner/repo/refs/tags/v1/.github/workflows/deploy.yml":
+				return []byte(`jobs: { rebuild: { uses: "./.github/workflows/build.yml" } }`), nil
+			}
+			return nil, errors.New("not found")
+		},
+	}
+
+	runner := NewWorkflowRunnerWithClient(client)
+	output, err := runner.RunWorkflowAnalysis(context.Background(), "https://github.com/owner/repo/blob/v1/.github/workflows/ci.yml", 10, "flowchart")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "cycle")
+
+	output, err = runner.SetStrict(true).RunWorkflowAnalysis(context.Background(), "https://github.com/owner/repo/blob/v1/.github/workflows/ci.yml", 10, "flowchart")
+	assert.NotEmpty(t, output, "the diagram is rendered in strict mode too")
+	var unresolvedErr *UnresolvedError
+	assert.ErrorAs(t, err, &unresolvedErr)
+	assert.Empty(t, unresolvedErr.Problems)
+	assert.Len(t, unresolvedErr.Cycles, 1)
+	assert.Contains(t, err.Error(), "1 reference cycle(s)")
+	assert.Contains(t, err.Error(), "./.github/workflows/build.yml -> ./.github/workflows/deploy.yml -> ./.github/workflows/build.yml")
+	assert.NotContains(t, err.Error(), "could not be resolved")
+}
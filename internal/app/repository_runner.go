@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+)
+
+// repositorySource describes the set of workflows of a repository to analyze.
+type repositorySource struct {
+	name  string
+	owner string
+	repo  string
+	urls  []string
+}
+
+// repositoryWorkflow is a parsed workflow of the repository, identified by its file name.
+type repositoryWorkflow struct {
+	file string
+	wf   *github.Workflow
+}
+
+// IsRepositorySource reports whether source is a local directory or an owner/repo[@ref] reference.
+func IsRepositorySource(source string) bool {
+	if info, err := os.Stat(source); err == nil {
+		return info.IsDir()
+	}
+	_, _, _, ok := github.ParseRepositoryRef(source)
+	return ok
+}
+
+// RunRepositoryAnalysis analyzes every workflow of a repository and generates a combined diagram,
+// highlighting reusable workflows shared by several callers and orphaned workflows.
+// The output options are validated before anything is downloaded.
+func (wr *WorkflowRunner) RunRepositoryAnalysis(ctx context.Context, source string, depth int, diagramType string) (string, error) {
+	renderer, err := wr.newRenderer(diagramType)
+	if err != nil {
+		return "", err
+	}
+	root, err := wr.AnalyzeRepository(ctx, source, depth)
+	if err != nil {
+		return "", err
+	}
+	output, err := renderer.Render(root)
+	if err != nil {
+		return "", err
+	}
+	return output, wr.checkProblems(root)
+}
+
+// AnalyzeRepository builds the combined dependency tree of every workflow of a repository: a repository
+// node whose children are the workflow trees, fetching the references up to depth.
+func (wr *WorkflowRunner) AnalyzeRepository(ctx context.Context, source string, depth int) (*github.UsesNode, error) {
+	src, err := wr.listRepositoryWorkflows(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var workflows []repositoryWorkflow
+	known := map[string]bool{}
+	for _, u := range src.urls {
+		data, err := wr.client.DownloadWorkflow(ctx, u)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to download workflow: %w", err)
+			}
+			slog.Warn("Failed to download workflow", "url", u, "error", err)
+			continue
+		}
+		wf, err := github.ParseWorkflowYAML(u, data)
+		if err != nil {
+			slog.Warn("Failed to parse workflow", "url", u, "error", err)
+			continue
+		}
+		file := workflowFileName(u)
+		known[file] = true
+		workflows = append(workflows, repositoryWorkflow{file: file, wf: wf})
+	}
+	if len(workflows) == 0 {
+		return nil, fmt.Errorf("no workflows found in %s", source)
+	}
+
+	// Workflows of the repository are rendered once and linked from their callers,
+	// so references to them are not expanded again.
+	inRepo := func(uses string) string {
+		file := github.WorkflowFileName(uses, src.owner, src.repo)
+		if !known[file] {
+			return ""
+		}
+		return file
+	}
+
+	callers := map[string][]string{}
+	for _, w := range workflows {
+		seen := map[string]bool{}
+		for _, jobName := range w.wf.JobNames() {
+			target := inRepo(w.wf.Jobs[jobName].Uses)
+			if target == "" || seen[target] {
+				continue
+			}
+			seen[target] = true
+			callers[target] = append(callers[target], w.file)
+		}
+	}
+
+	// A single resolver is shared by all workflows so that common references are fetched once.
+	resolver := github.NewResolver(wr.client, wr.concurrency)
+	fetchers := make([]func(context.Context, string) (*github.Workflow, error), len(workflows))
+	prefetcher := github.NewPrefetcher(wr.concurrency)
+	for i, w := range workflows {
+		fetch, err := wr.newFetcher(resolver, w.wf.URL)
+		if err != nil {
+			return nil, err
+		}
+		fetchers[i] = func(ctx context.Context, uses string) (*github.Workflow, error) {
+			if inRepo(uses) != "" {
+				return nil, nil
+			}
+			return fetch(ctx, uses)
+		}
+		prefetcher.Add(ctx, w.wf, fetchers[i], depth)
+	}
+	prefetcher.Wait()
+
+	root := &github.UsesNode{Name: src.name, UniqueID: src.name, Kind: github.NodeKindRepository}
+	var shared, orphaned []string
+	for i, w := range workflows {
+		fetcher := fetchers[i]
+		tree := github.BuildUsesTree(ctx, w.file, w.wf, fetcher, depth)
+		for _, job := range tree.Children {
+			if job.Job != nil {
+				if target := inRepo(job.Job.Uses); target != "" {
+					job.Calls = append(job.Calls, target)
+				}
+			}
+		}
+
+		switch n := len(callers[w.file]); {
+		case n > 1:
+			shared = append(shared, w.file)
+			tree.Name = fmt.Sprintf("%s [shared: %d callers]", w.file, n)
+		case n == 0 && !hasEventTrigger(w.wf):
+			orphaned = append(orphaned, w.file)
+			tree.Name = w.file + " [orphaned]"
+		}
+		root.Children = append(root.Children, tree)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("analysis aborted: %w", err)
+	}
+
+	slog.Info("Repository workflows analyzed", "workflows", len(workflows), "shared", shared, "orphaned", orphaned)
+	reportMutableRefs(root)
+	return root, nil
+}
+
+// listRepositoryWorkflows lists the workflow files of a local directory or a remote repository.
+func (wr *WorkflowRunner) listRepositoryWorkflows(ctx context.Context, source string) (*repositorySource, error) {
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		files, err := github.ListLocalWorkflows(source)
+		if err != nil {
+			return nil, err
+		}
+		name := source
+		if abs, err := filepath.Abs(source); err == nil {
+			name = filepath.Base(abs)
+		}
+		return &repositorySource{name: name, urls: files}, nil
+	}
+
+	owner, repo, ref, ok := github.ParseRepositoryRef(source)
+	if !ok {
+		return nil, fmt.Errorf("invalid repository: %s", source)
+	}
+	lister, ok := wr.client.(github.WorkflowLister)
+	if !ok {
+		return nil, fmt.Errorf("listing remote workflows is not supported by this client")
+	}
+	urls, err := lister.ListWorkflows(ctx, owner, repo, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	return &repositorySource{name: source, owner: owner, repo: repo, urls: urls}, nil
+}
+
+// hasEventTrigger reports whether the workflow can be started by something other than a workflow_call.
+func hasEventTrigger(wf *github.Workflow) bool {
+	for _, trigger := range wf.On {
+		if trigger.Event != "workflow_call" {
+			return true
+		}
+	}
+	return false
+}
+
+// workflowFileName returns the file name of a workflow URL or local path.
+func workflowFileName(u string) string {
+	if parsed, err := url.Parse(u); err == nil && parsed.Scheme != "" && parsed.Path != "" {
+		return path.Base(parsed.Path)
+	}
+	return filepath.Base(u)
+}
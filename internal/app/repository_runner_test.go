@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeWorkflows(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	dir := filepath.Join(root, ".github", "workflows")
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return root
+}
+
+func TestIsRepositorySource(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ci.yml")
+	assert.NoError(t, os.WriteFile(file, []byte("jobs: {}"), 0o644))
+
+	assert.True(t, IsRepositorySource(dir))
+	assert.True(t, IsRepositorySource("owner/repo@main"))
+	assert.False(t, IsRepositorySource(file))
+	assert.False(t, IsRepositorySource("https://github.com/owner/repo/blob/main/.github/workflows/ci.yml"))
+	// Paths that do not exist are not repository references.
+	assert.False(t, IsRepositorySource("./missing"))
+	assert.False(t, IsRepositorySource("../missing"))
+	assert.False(t, IsRepositorySource("missing/ci.yml"))
+	assert.False(t, IsRepositorySource("missing/dir/ci.yml"))
+
+	// They are read as local workflow files, and fail as such.
+	_, err := NewWorkflowRunner("").RunWorkflowAnalysis(context.Background(), "./missing", 1, "flowchart")
+	assert.ErrorContains(t, err, "no such file or directory")
+}
+
+func TestRunRepositoryAnalysis_SharedAndOrphaned(t *testing.T) {
+	root := writeWorkflows(t, map[string]string{
+		"ci.yml": `
+on: push
+jobs:
+  test:
+    uses: ./.github/workflows/build.yml
+`,
+		"release.yml": `
+on: {push: {tags: ['v*']}}
+jobs:
+  build:
+    uses: ./.github/workflows/build.yml
+`,
+		"build.yml": `
+on: workflow_call
+jobs:
+  compile: {}
+`,
+		"old.yml": `
+on: workflow_call
+jobs:
+  legacy: {}
+`,
+	})
+
+	runner := NewWorkflowRunnerWithClient(github.NewClient(""))
+	output, err := runner.RunRepositoryAnalysis(context.Background(), root, 2, "flowchart")
+	assert.NoError(t, err)
+	assert.Contains(t, output, `label: "build.yml [shared: 2 callers]"`)
+	assert.Contains(t, output, `label: "old.yml [orphaned]"`)
+	assert.Contains(t, output, `label: "ci.yml"`)
+	assert.Contains(t, output, "==>|uses|")
+	assert.NotContains(t, output, `label: "ci.yml [orphaned]"`)
+}
+
+func TestRunRepositoryAnalysis_NoWorkflows(t *testing.T) {
+	runner := NewWorkflowRunnerWithClient(github.NewClient(""))
+	_, err := runner.RunRepositoryAnalysis(context.Background(), t.TempDir(), 2, "flowchart")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no workflows found")
+}
+
+func TestRunRepositoryAnalysis_RemoteUnsupportedClient(t *testing.T) {
+	runner := NewWorkflowRunnerWithClient(&mockClient{})
+	_, err := runner.RunRepositoryAnalysis(context.Background(), "owner/repo@main", 2, "flowchart")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported")
+}
@@ -1,26 +1,24 @@
-//go:build integration
-// +build integration
-
 package app
 
 import (
-	"fmt"
+	"context"
+	"path/filepath"
 	"testing"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestRunWorkflowAnalysis_Integration(t *testing.T) {
+	// The fixtures call each other, from reusable-wf1.yml to reusable-wf5.yml; strict mode fails
+	// if any of them is not resolved.
+	runner := NewWorkflowRunnerWithClient(github.NewClient("")).SetStrict(true)
 
-	runner := NewWorkflowRunner("")
-
-	workflowURL := "https://github.com/leocomelli/wk2mmd/blob/main/.github/test-workflows/reusable-wf1.yml"
-	diagramType := "flowchart"
-	depth := 10
-
-	output, err := runner.RunWorkflowAnalysis(workflowURL, depth, diagramType)
-	if err != nil {
-		t.Fatalf("Failed to run workflow analysis: %v", err)
+	workflowURL := filepath.Join("testdata", ".github", "workflows", "reusable-wf1.yml")
+	output, err := runner.RunWorkflowAnalysis(context.Background(), workflowURL, 10, "flowchart")
+	assert.NoError(t, err)
+	for _, name := range []string{"reusable-wf2.yml", "reusable-wf3.yml", "reusable-wf4.yml", "reusable-wf5.yml"} {
+		assert.Contains(t, output, name)
 	}
-
-	fmt.Println("--------------------------------")
-	fmt.Println(output)
+	assert.NotContains(t, output, "invalid reference")
 }
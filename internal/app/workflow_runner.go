@@ -1,8 +1,11 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 
 	"github.com/leocomelli/wk2mmd/internal/diagram"
 	"github.com/leocomelli/wk2mmd/internal/github"
@@ -10,65 +13,174 @@ import (
 
 // WorkflowRunner encapsulates the logic for analyzing workflows.
 type WorkflowRunner struct {
-	client github.WorkflowDownloader
+	client      github.WorkflowDownloader
+	format      string
+	concurrency int
+	strict      bool
+	view        string
+	detail      string
+	theme       string
+	legend      bool
 }
 
 // NewWorkflowRunner creates a WorkflowRunner for normal use.
 func NewWorkflowRunner(token string) *WorkflowRunner {
-	return &WorkflowRunner{client: github.NewClient(token)}
+	return NewWorkflowRunnerWithClient(github.NewClient(token))
 }
 
-// NewWorkflowRunnerWithClient creates a WorkflowRunner for testing.
+// NewWorkflowRunnerWithClient creates a WorkflowRunner with a custom downloader
+// (e.g. a cached client, or a mock for testing).
 func NewWorkflowRunnerWithClient(client github.WorkflowDownloader) *WorkflowRunner {
-	return &WorkflowRunner{client: client}
+	return &WorkflowRunner{client: client, concurrency: github.DefaultConcurrency}
+}
+
+// SetFormat sets the output format (mermaid, dot, plantuml, d2, json or yaml) and returns the runner for chaining.
+func (wr *WorkflowRunner) SetFormat(format string) *WorkflowRunner {
+	wr.format = format
+	return wr
+}
+
+// SetConcurrency sets how many references are fetched in parallel and returns the runner for chaining.
+func (wr *WorkflowRunner) SetConcurrency(concurrency int) *WorkflowRunner {
+	wr.concurrency = concurrency
+	return wr
+}
+
+// SetView sets the view of the dependency graph that is rendered (tree or dag) and returns the runner for chaining.
+func (wr *WorkflowRunner) SetView(view string) *WorkflowRunner {
+	wr.view = view
+	return wr
+}
+
+// SetDetail sets the detail level of the rendered graph (jobs, steps or full) and returns the runner for chaining.
+func (wr *WorkflowRunner) SetDetail(detail string) *WorkflowRunner {
+	wr.detail = detail
+	return wr
+}
+
+// SetTheme sets the theme of the Mermaid flowcharts (a built-in theme name or the path of a theme file) and
+// returns the runner for chaining.
+func (wr *WorkflowRunner) SetTheme(theme string) *WorkflowRunner {
+	wr.theme = theme
+	return wr
+}
+
+// SetLegend adds a legend of the kinds of nodes to the Mermaid flowcharts and returns the runner for chaining.
+func (wr *WorkflowRunner) SetLegend(legend bool) *WorkflowRunner {
+	wr.legend = legend
+	return wr
 }
 
 // RunWorkflowAnalysis orchestrates the download, parsing, recursive fetch, and tree/mermaid generation.
-func (wr *WorkflowRunner) RunWorkflowAnalysis(workflowURL string, depth int, diagramType string) (string, error) {
+// Canceling ctx aborts the downloads in flight and the analysis returns the context error.
+// The output options are validated before anything is downloaded.
+func (wr *WorkflowRunner) RunWorkflowAnalysis(ctx context.Context, workflowURL string, depth int, diagramType string) (string, error) {
+	renderer, err := wr.newRenderer(diagramType)
+	if err != nil {
+		return "", err
+	}
+	tree, err := wr.AnalyzeWorkflow(ctx, workflowURL, depth)
+	if err != nil {
+		return "", err
+	}
+	output, err := renderer.Render(tree)
+	if err != nil {
+		return "", err
+	}
+	return output, wr.checkProblems(tree)
+}
 
-	data, err := wr.client.DownloadWorkflow(workflowURL)
+// AnalyzeWorkflow downloads and parses the workflow, and builds its dependency tree, fetching the
+// references up to depth. Unresolved references are recorded on the nodes of the tree.
+func (wr *WorkflowRunner) AnalyzeWorkflow(ctx context.Context, workflowURL string, depth int) (*github.UsesNode, error) {
+	data, err := wr.client.DownloadWorkflow(ctx, workflowURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to download workflow: %w", err)
+		return nil, fmt.Errorf("failed to download workflow: %w", err)
 	}
 	slog.Debug("Workflow content", "content", string(data[:min(300, len(data))]))
 
 	wf, err := github.ParseWorkflowYAML(workflowURL, data)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse workflow YAML: %w", err)
+		return nil, fmt.Errorf("failed to parse workflow YAML: %w", err)
+	}
+
+	fetcher, err := wr.newFetcher(github.NewResolver(wr.client, wr.concurrency), workflowURL)
+	if err != nil {
+		return nil, err
 	}
+	github.Prefetch(ctx, wf, fetcher, depth, wr.concurrency)
+	allUses := github.CollectAllUses(ctx, wf, fetcher, depth)
+
+	slog.Info("All uses found recursively", "uses", len(allUses))
 
-	// Recursively collect all uses and build the tree
-	owner, repo, branch := extractRepoInfo(workflowURL)
-	slog.Debug("Extracted repo info", "owner", owner, "repo", repo, "branch", branch)
+	tree := github.BuildUsesTree(ctx, "workflow", wf, fetcher, depth)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("analysis aborted: %w", err)
+	}
+	reportMutableRefs(tree)
+	return tree, nil
+}
 
-	fetcher := func(uses string) *github.Workflow {
-		ar, ok := github.ParseActionRef(uses, owner, repo, branch)
-		if !ok {
-			return nil
+// newFetcher returns a function that resolves the 'uses' references of the given workflow and fetches them
+// through resolver, so that each reference is downloaded only once. Local references are resolved against
+// the repository of the workflow; those of the documents it references are resolved by the tree traversal
+// against the repositories of those documents.
+func (wr *WorkflowRunner) newFetcher(resolver *github.Resolver, workflowURL string) (func(context.Context, string) (*github.Workflow, error), error) {
+	repo, err := repositoryOf(workflowURL, github.HostOf(wr.client))
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("Resolving local references against", "owner", repo.Owner, "repo", repo.Name, "ref", repo.Ref, "dir", repo.Dir)
+
+	// The references are fetched by the prefetch, the collection of the uses and the tree traversal:
+	// invalid ones are only reported once.
+	var reported sync.Map
+	return func(ctx context.Context, uses string) (*github.Workflow, error) {
+		ar, err := github.ParseActionRef(uses, repo)
+		if err != nil {
+			if _, seen := reported.LoadOrStore(uses, true); !seen {
+				slog.Warn("Invalid reference", "error", err)
+			}
+			return nil, &github.ResolveError{Uses: uses, Reason: github.UnresolvedInvalid, Err: err}
 		}
-		wf := github.FetchActionWorkflow(wr.client, ar)
-		if wf != nil {
-			slog.Debug("Fetched reusable workflow", "uses", uses, "jobs", len(wf.Jobs))
-		} else {
-			slog.Error("Failed to fetch reusable workflow", "uses", uses)
+		wf, err := resolver.Resolve(ctx, ar)
+		if err != nil {
+			slog.Debug("Failed to fetch reusable workflow", "uses", uses, "error", err)
+			return nil, err
 		}
-		return wf
-	}
-	allUses := github.CollectAllUses(wf, fetcher, depth)
+		if wf == nil {
+			// Docker images have nothing to expand.
+			return nil, nil
+		}
+		slog.Debug("Fetched reusable workflow", "uses", uses, "jobs", len(wf.Jobs))
+		return wf, nil
+	}, nil
+}
 
-	slog.Info("All uses found recursively", "uses", len(allUses))
+// newRenderer returns the renderer of the given diagram type, in the runner output format.
+func (wr *WorkflowRunner) newRenderer(diagramType string) (diagram.Renderer, error) {
+	var opts []diagram.Option
+	if wr.view != "" {
+		opts = append(opts, diagram.WithView(wr.view))
+	}
+	if wr.detail != "" {
+		opts = append(opts, diagram.WithDetail(wr.detail))
+	}
+	if (wr.format == "" || wr.format == diagram.FormatMermaid) && diagramType == "flowchart" {
+		// Themes only style the Mermaid flowcharts.
+		theme, err := diagram.LoadTheme(wr.theme)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, diagram.WithTheme(theme), diagram.WithLegend(wr.legend))
+	}
+	return diagram.NewRenderer(wr.format, diagramType, opts...)
+}
 
-	tree := github.BuildUsesTree("workflow", wf, fetcher, depth, map[string]bool{})
-
-	// Mermaid diagram generation
-	fmt.Println("\nMermaid diagram:")
-	switch diagramType {
-	case "sequence":
-		return diagram.GenerateMermaidSequence(tree), nil
-	case "flowchart":
-		return diagram.GenerateMermaidFlowchart(tree), nil
-	default:
-		return "", fmt.Errorf("invalid diagram type: %s", diagramType)
+// reportMutableRefs warns about the references of the tree that point at a branch rather than a tag or commit.
+func reportMutableRefs(tree *github.UsesNode) {
+	if refs := github.MutableRefs(tree); len(refs) > 0 {
+		slog.Warn("References pointing at mutable branches", "count", len(refs), "uses", refs)
 	}
 }
 
@@ -79,9 +191,22 @@ func min(a, b int) int {
 	return b
 }
 
-// extractRepoInfo tries to extract owner, repo, branch from a raw.githubusercontent.com URL.
-func extractRepoInfo(url string) (owner, repo, branch string) {
-	re := github.ExtractRepoInfoRegex()
+// repositoryOf returns the repository of the workflow at the given URL or local path, against which
+// its local references are resolved. Returns an error for a URL that is not a file URL of host.
+func repositoryOf(workflowURL, host string) (github.Repository, error) {
+	if strings.HasPrefix(workflowURL, "https://") || strings.HasPrefix(workflowURL, "http://") {
+		owner, repo, ref := extractRepoInfo(workflowURL, host)
+		if owner == "" {
+			return github.Repository{}, fmt.Errorf("cannot resolve the local references of %s: not a file URL of %s", workflowURL, github.NormalizeHost(host))
+		}
+		return github.Repository{Owner: owner, Name: repo, Ref: ref}, nil
+	}
+	return github.Repository{Dir: github.LocalRepositoryRoot(workflowURL)}, nil
+}
+
+// extractRepoInfo tries to extract owner, repo, branch from a file URL of the given GitHub host.
+func extractRepoInfo(url, host string) (owner, repo, branch string) {
+	re := github.ExtractRepoInfoRegex(host)
 	matches := re.FindStringSubmatch(url)
 	if len(matches) == 4 {
 		return matches[1], matches[2], matches[3]
@@ -1,9 +1,17 @@
 package app
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/leocomelli/wk2mmd/internal/github"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -11,7 +19,7 @@ type mockClient struct {
 	DownloadWorkflowFunc func(url string) ([]byte, error)
 }
 
-func (m *mockClient) DownloadWorkflow(url string) ([]byte, error) {
+func (m *mockClient) DownloadWorkflow(ctx context.Context, url string) ([]byte, error) {
 	return m.DownloadWorkflowFunc(url)
 }
 
@@ -22,7 +30,7 @@ func TestRunWorkflowAnalysis_Success(t *testing.T) {
 		},
 	}
 	runner := NewWorkflowRunnerWithClient(client)
-	_, err := runner.RunWorkflowAnalysis("https://raw.githubusercontent.com/owner/repo/branch/file.yml", 2, "flowchart")
+	_, err := runner.RunWorkflowAnalysis(context.Background(), "https://raw.githubusercontent.com/owner/repo/branch/file.yml", 2, "flowchart")
 	assert.NoError(t, err)
 }
 
@@ -33,7 +41,7 @@ func TestRunWorkflowAnalysis_DownloadFail(t *testing.T) {
 		},
 	}
 	runner := NewWorkflowRunnerWithClient(client)
-	_, err := runner.RunWorkflowAnalysis("https://raw.githubusercontent.com/owner/repo/branch/file.yml", 2, "flowchart")
+	_, err := runner.RunWorkflowAnalysis(context.Background(), "https://raw.githubusercontent.com/owner/repo/branch/file.yml", 2, "flowchart")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to download workflow")
 }
@@ -45,7 +53,262 @@ func TestRunWorkflowAnalysis_ParseFail(t *testing.T) {
 		},
 	}
 	runner := NewWorkflowRunnerWithClient(client)
-	_, err := runner.RunWorkflowAnalysis("https://raw.githubusercontent.com/owner/repo/branch/file.yml", 2, "flowchart")
+	_, err := runner.RunWorkflowAnalysis(context.Background(), "https://raw.githubusercontent.com/owner/repo/branch/file.yml", 2, "flowchart")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to parse workflow YAML")
 }
+
+func TestRunWorkflowAnalysis_Format(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			return []byte(`jobs: { job: { steps: [ { run: "make" } ] } }`), nil
+		},
+	}
+	runner := NewWorkflowRunnerWithClient(client).SetFormat("dot")
+	output, err := runner.RunWorkflowAnalysis(context.Background(), "workflow.yml", 2, "flowchart")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "digraph workflow")
+
+	_, err = runner.SetFormat("svg").RunWorkflowAnalysis(context.Background(), "workflow.yml", 2, "flowchart")
+	assert.EqualError(t, err, "invalid format: svg")
+}
+
+func TestRunWorkflowAnalysis_DockerStep(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			return []byte(`jobs: { build: { steps: [ { uses: "docker://alpine:3" } ] } }`), nil
+		},
+	}
+	runner := NewWorkflowRunnerWithClient(client).SetStrict(true)
+	output, err := runner.RunWorkflowAnalysis(context.Background(), "workflow.yml", 3, "flowchart")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "docker://alpine:3")
+}
+
+func TestRunWorkflowAnalysis_InvalidReferenceReportedOnce(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			return []byte(`jobs: { call: { uses: "owner/repo/.github/workflows/build.yml" } }`), nil
+		},
+	}
+	_, err := NewWorkflowRunnerWithClient(client).RunWorkflowAnalysis(context.Background(), "workflow.yml", 2, "flowchart")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(logs.String(), "Invalid reference"))
+}
+
+func TestRunWorkflowAnalysis_InvalidOptionsBeforeDownload(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			t.Errorf("unexpected download of %s", url)
+			return nil, errors.New("unexpected download")
+		},
+	}
+	runner := NewWorkflowRunnerWithClient(client).SetFormat("foo")
+	_, err := runner.RunWorkflowAnalysis(context.Background(), "https://raw.githubusercontent.com/owner/repo/branch/file.yml", 2, "sequence")
+	assert.EqualError(t, err, "invalid format: foo")
+
+	runner = NewWorkflowRunnerWithClient(client).SetView("graph")
+	_, err = runner.RunRepositoryAnalysis(context.Background(), "owner/repo", 2, "flowchart")
+	assert.EqualError(t, err, "invalid view: graph")
+
+	runner = NewWorkflowRunnerWithClient(client).SetDetail("all")
+	_, err = runner.RunWorkflowAnalysis(context.Background(), "https://raw.githubusercontent.com/owner/repo/branch/file.yml", 2, "flowchart")
+	assert.EqualError(t, err, "invalid detail: all")
+}
+
+func TestRunWorkflowAnalysis_FetchesEachReferenceOnce(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			mu.Lock()
+			calls[url]++
+			mu.Unlock()
+			switch url {
+			case "workflow.yml":
+				return []byte(`jobs: { a: { uses: "./.github/workflows/reusable.yml" }, b: { uses: "./.github/workflows/reusable.yml" } }`), nil
+			case "./.github/workflows/reusable.yml":
+				return []byte(`jobs: { build: { steps: [ { run: "make" } ] } }`), nil
+			}
+			return nil, errors.New("not found")
+		},
+	}
+	runner := NewWorkflowRunnerWithClient(client).SetConcurrency(4)
+	_, err := runner.RunWorkflowAnalysis(context.Background(), "workflow.yml", 3, "flowchart")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls["./.github/workflows/reusable.yml"])
+}
+
+func TestRunWorkflowAnalysis_Canceled(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			return []byte(`jobs: { a: { uses: "./.github/workflows/reusable.yml" } }`), nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runner := NewWorkflowRunnerWithClient(client)
+	_, err := runner.RunWorkflowAnalysis(ctx, "workflow.yml", 2, "flowchart")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunWorkflowAnalysis_LocalReferencesFromRepositoryRoot(t *testing.T) {
+	root := writeWorkflows(t, map[string]string{
+		"ci.yml": "jobs:\n  build:\n    steps:\n      - uses: ./actions/setup\n",
+	})
+	action := filepath.Join(root, "actions", "setup")
+	assert.NoError(t, os.MkdirAll(action, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(action, "action.yml"), []byte("name: Setup\nruns:\n  using: node20\n  main: index.js\n"), 0o644))
+
+	// The test runs from internal/app, not from the repository root.
+	runner := NewWorkflowRunnerWithClient(github.NewClient("")).SetStrict(true)
+	_, err := runner.RunWorkflowAnalysis(context.Background(), filepath.Join(root, ".github", "workflows", "ci.yml"), 2, "flowchart")
+	assert.NoError(t, err)
+}
+
+func TestRunWorkflowAnalysis_LocalReferencesOfRemoteWorkflow(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			mu.Lock()
+			requested = append(requested, url)
+			mu.Unlock()
+			switch url {
+			case "https://github.com/owner/repo/blob/v1/.github/workflows/ci.yml":
+				return []byte(`jobs: { call: { uses: "./.github/workflows/build.yml" } }`), nil
+			case "https://raw.githubusercontent.com/owner/repo/refs/tags/v1/.github/workflows/build.yml":
+				return []byte(`jobs: { compile: { runs-on: ubuntu-latest } }`), nil
+			}
+			return nil, &github.StatusError{StatusCode: 404}
+		},
+	}
+	runner := NewWorkflowRunnerWithClient(client).SetStrict(true)
+	_, err := runner.RunWorkflowAnalysis(context.Background(), "https://github.com/owner/repo/blob/v1/.github/workflows/ci.yml", 2, "flowchart")
+	assert.NoError(t, err)
+	assert.NotContains(t, requested, "./.github/workflows/build.yml")
+}
+
+func TestRunWorkflowAnalysis_LocalReferencesOfReusableWorkflow(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			mu.Lock()
+			requested = append(requested, url)
+			mu.Unlock()
+			switch url {
+			case "https://github.com/owner/repo/blob/main/.github/workflows/ci.yml":
+				return []byte(`jobs: { call: { uses: "org/shared/.github/workflows/a.yml@v1" } }`), nil
+			case "https://raw.githubusercontent.com/org/shared/refs/tags/v1/.github/workflows/a.yml":
+				return []byte(`jobs: { call: { uses: "./.github/workflows/b.yml" } }`), nil
+			case "https://raw.githubusercontent.com/org/shared/refs/tags/v1/.github/workflows/b.yml":
+				return []byte(`jobs: { compile: { runs-on: ubuntu-latest } }`), nil
+			}
+			return nil, &github.StatusError{StatusCode: 404}
+		},
+	}
+	// The local reference of org/shared resolves in org/shared at v1, not in the repository of ci.yml.
+	runner := NewWorkflowRunnerWithClient(client).SetStrict(true)
+	_, err := runner.RunWorkflowAnalysis(context.Background(), "https://github.com/owner/repo/blob/main/.github/workflows/ci.yml", 3, "flowchart")
+	assert.NoError(t, err)
+	assert.Contains(t, requested, "https://raw.githubusercontent.com/org/shared/refs/tags/v1/.github/workflows/b.yml")
+	for _, url := range requested {
+		assert.NotContains(t, url, "owner/repo/refs", url)
+	}
+}
+
+func TestRunWorkflowAnalysis_LocalActionsOfReusableWorkflow(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			mu.Lock()
+			requested = append(requested, url)
+			mu.Unlock()
+			switch url {
+			case "https://github.com/owner/repo/blob/main/.github/workflows/ci.yml":
+				return []byte(`jobs: { call: { uses: "org/shared/.github/workflows/a.yml@v1" } }`), nil
+			case "https://raw.githubusercontent.com/org/shared/refs/tags/v1/.github/workflows/a.yml":
+				return []byte(`jobs: { build: { steps: [ { uses: "./actions/setup" } ] } }`), nil
+			case "https://raw.githubusercontent.com/owner/repo/refs/heads/main/actions/setup/action.yml":
+				return []byte(`{ name: Setup, runs: { using: composite, steps: [ { uses: "./actions/cache" } ] } }`), nil
+			case "https://raw.githubusercontent.com/owner/repo/refs/heads/main/actions/cache/action.yml":
+				return []byte(`{ name: Cache, runs: { using: node20, main: index.js } }`), nil
+			}
+			return nil, &github.StatusError{StatusCode: 404}
+		},
+	}
+	// The local actions of org/shared resolve in the repository checked out by the run, the one of ci.yml.
+	runner := NewWorkflowRunnerWithClient(client).SetStrict(true)
+	_, err := runner.RunWorkflowAnalysis(context.Background(), "https://github.com/owner/repo/blob/main/.github/workflows/ci.yml", 4, "flowchart")
+	assert.NoError(t, err, "requested: %v", requested)
+	for _, url := range requested {
+		assert.NotContains(t, url, "org/shared/refs/tags/v1/actions", url)
+	}
+}
+
+func TestRepositoryOf(t *testing.T) {
+	repo, err := repositoryOf("https://github.com/owner/repo/blob/v1/.github/workflows/ci.yml", github.DefaultHost)
+	assert.NoError(t, err)
+	assert.Equal(t, github.Repository{Owner: "owner", Name: "repo", Ref: "v1"}, repo)
+
+	repo, err = repositoryOf("ci.yml", github.DefaultHost)
+	assert.NoError(t, err)
+	assert.False(t, repo.IsRemote())
+
+	_, err = repositoryOf("https://example.com/workflows/ci.yml", github.DefaultHost)
+	assert.EqualError(t, err, "cannot resolve the local references of https://example.com/workflows/ci.yml: not a file URL of github.com")
+}
+
+func TestRunWorkflowAnalysis_View(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			switch url {
+			case "workflow.yml":
+				return []byte(`jobs: { a: { uses: "./.github/workflows/reusable.yml" }, b: { uses: "./.github/workflows/reusable.yml" } }`), nil
+			case "./.github/workflows/reusable.yml":
+				return []byte(`jobs: { build: { steps: [ { run: "make" } ] } }`), nil
+			}
+			return nil, errors.New("not found")
+		},
+	}
+	runner := NewWorkflowRunnerWithClient(client).SetFormat("dot")
+	output, err := runner.RunWorkflowAnalysis(context.Background(), "workflow.yml", 2, "flowchart")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(output, `[label="build"]`))
+
+	output, err = runner.SetView("dag").RunWorkflowAnalysis(context.Background(), "workflow.yml", 2, "flowchart")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(output, `[label="build"]`))
+
+	_, err = runner.SetView("list").RunWorkflowAnalysis(context.Background(), "workflow.yml", 2, "flowchart")
+	assert.EqualError(t, err, "invalid view: list")
+}
+
+func TestRunWorkflowAnalysis_ThemeAndLegend(t *testing.T) {
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			return []byte(`jobs: { build: { steps: [ { uses: "actions/checkout@v4" } ] } }`), nil
+		},
+	}
+	runner := NewWorkflowRunnerWithClient(client).SetTheme("dark").SetLegend(true)
+	output, err := runner.RunWorkflowAnalysis(context.Background(), "workflow.yml", 1, "flowchart")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "theme: dark")
+	assert.Contains(t, output, "subgraph legend [Legend]")
+
+	_, err = runner.SetTheme("solarized").RunWorkflowAnalysis(context.Background(), "workflow.yml", 1, "flowchart")
+	assert.ErrorContains(t, err, `unknown theme "solarized"`)
+
+	// Themes only apply to Mermaid flowcharts.
+	_, err = runner.RunWorkflowAnalysis(context.Background(), "workflow.yml", 1, "sequence")
+	assert.NoError(t, err)
+	_, err = runner.SetFormat("dot").RunWorkflowAnalysis(context.Background(), "workflow.yml", 1, "flowchart")
+	assert.NoError(t, err)
+}
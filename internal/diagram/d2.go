@@ -0,0 +1,65 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
+)
+
+// d2EdgeAttrs maps edge kinds to D2 edge labels and styles.
+var d2EdgeAttrs = map[string]string{
+	graph.EdgeTypeContains: "",
+	graph.EdgeTypeNeeds:    ": needs {style.stroke-dash: 3}",
+	graph.EdgeTypeUses:     ": uses {style.stroke-width: 3}",
+	graph.EdgeTypeTriggers: "",
+	graph.EdgeTypeCycle:    ": cycle {style.stroke: red; style.font-color: red; style.stroke-width: 3}",
+	graph.EdgeTypeNext:     "",
+}
+
+// GenerateD2 generates a D2 diagram from a UsesNode tree.
+func GenerateD2(root *github.UsesNode) string {
+	return generateD2(flattenTree(root))
+}
+
+// generateD2 generates a D2 diagram from a flattened dependency graph.
+func generateD2(g *flatGraph) string {
+	var sb strings.Builder
+	sb.WriteString("title: Workflow Graph {near: top-center; shape: text}\n")
+	sb.WriteString("direction: down\n")
+	for _, n := range g.Nodes {
+		attrs := ""
+		if n.Trigger {
+			attrs = " {shape: oval}"
+		}
+		if style := d2UnresolvedStyle(n.Unresolved); style != "" {
+			attrs = " {" + style + "}"
+		}
+		fmt.Fprintf(&sb, "%s: %s%s\n", n.ID, d2Quote(strings.Join(n.Lines, "\n")), attrs)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "%s -> %s%s\n", e.From, e.To, d2EdgeAttrs[e.Kind])
+	}
+	return sb.String()
+}
+
+// d2UnresolvedStyle returns the style of a node whose reference could not be resolved for the given reason.
+func d2UnresolvedStyle(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case github.UnresolvedDepthLimit:
+		return "style.stroke-dash: 3; style.stroke: gray"
+	default:
+		return "style.stroke-dash: 3; style.stroke: red; style.font-color: red"
+	}
+}
+
+// d2Quote returns s as a D2 double quoted string.
+func d2Quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,22 @@
+package diagram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateD2(t *testing.T) {
+	result := GenerateD2(sampleTree())
+	for _, want := range []string{
+		`n0: "root"`,
+		`n4: "push\nbranches: main" {shape: oval}`,
+		"n0 -> n1\n",
+		"n1 -> n2: needs {style.stroke-dash: 3}",
+		"n2 -> n3: uses {style.stroke-width: 3}",
+		"n4 -> n0\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+}
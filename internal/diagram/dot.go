@@ -0,0 +1,66 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
+)
+
+// dotEdgeAttrs maps edge kinds to Graphviz edge attributes.
+var dotEdgeAttrs = map[string]string{
+	graph.EdgeTypeContains: "",
+	graph.EdgeTypeNeeds:    ` [style=dashed, label="needs"]`,
+	graph.EdgeTypeUses:     ` [penwidth=2, label="uses"]`,
+	graph.EdgeTypeTriggers: "",
+	graph.EdgeTypeCycle:    ` [color=red, fontcolor=red, penwidth=2, label="cycle", constraint=false]`,
+	graph.EdgeTypeNext:     "",
+}
+
+// GenerateDOT generates a Graphviz DOT digraph from a UsesNode tree.
+func GenerateDOT(root *github.UsesNode) string {
+	return generateDOT(flattenTree(root))
+}
+
+// generateDOT generates a Graphviz DOT digraph from a flattened dependency graph.
+func generateDOT(g *flatGraph) string {
+	var sb strings.Builder
+	sb.WriteString("digraph workflow {\n")
+	sb.WriteString("    label=\"Workflow Graph\";\n")
+	sb.WriteString("    rankdir=TB;\n")
+	sb.WriteString("    node [shape=box];\n")
+	for _, n := range g.Nodes {
+		attrs := ""
+		if n.Trigger {
+			attrs = ", shape=oval"
+		}
+		attrs += dotUnresolvedAttrs(n.Unresolved)
+		fmt.Fprintf(&sb, "    %s [label=%s%s];\n", n.ID, dotQuote(strings.Join(n.Lines, "\n")), attrs)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "    %s -> %s%s;\n", e.From, e.To, dotEdgeAttrs[e.Kind])
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotUnresolvedAttrs returns the attributes of a node whose reference could not be resolved for the given reason.
+func dotUnresolvedAttrs(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case github.UnresolvedDepthLimit:
+		return ", style=dashed, color=gray"
+	default:
+		return ", style=dashed, color=red, fontcolor=red"
+	}
+}
+
+// dotQuote returns s as a DOT quoted string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,59 @@
+package diagram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+)
+
+func TestGenerateDOT(t *testing.T) {
+	result := GenerateDOT(sampleTree())
+	for _, want := range []string{
+		"digraph workflow {",
+		`n0 [label="root"];`,
+		`n4 [label="push\nbranches: main", shape=oval];`,
+		"n0 -> n1;",
+		`n1 -> n2 [style=dashed, label="needs"];`,
+		`n2 -> n3 [penwidth=2, label="uses"];`,
+		"n4 -> n0;",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+}
+
+func TestDotQuote(t *testing.T) {
+	if got := dotQuote(`say "hi"`); got != `"say \"hi\""` {
+		t.Errorf("Unexpected quoting: %s", got)
+	}
+}
+
+func TestGenerateDOT_Unresolved(t *testing.T) {
+	root := &github.UsesNode{Name: "root", UniqueID: "root", Children: []*github.UsesNode{
+		{Name: "call", UniqueID: "root/call", Uses: "./missing.yml", Unresolved: &github.ResolveError{Reason: github.UnresolvedNotFound}},
+		{Name: "deep", UniqueID: "root/deep", Uses: "./deep.yml", Unresolved: &github.ResolveError{Reason: github.UnresolvedDepthLimit}},
+	}}
+	result := GenerateDOT(root)
+	for _, want := range []string{
+		`n1 [label="call\nunresolved: not found", style=dashed, color=red, fontcolor=red];`,
+		`n2 [label="deep\nunresolved: depth limit", style=dashed, color=gray];`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+}
+
+func TestGenerateDOT_Cycle(t *testing.T) {
+	root := &github.UsesNode{Name: "root", UniqueID: "root", Children: []*github.UsesNode{
+		{Name: "a", UniqueID: "root/a", Uses: "./a.yml", Children: []*github.UsesNode{
+			{Name: "back", UniqueID: "root/a/back", Uses: "./a.yml", Cycle: []string{"./a.yml", "./a.yml"}, CycleTo: "root/a"},
+		}},
+	}}
+	result := GenerateDOT(root)
+	if want := `n2 -> n1 [color=red, fontcolor=red, penwidth=2, label="cycle", constraint=false];`; !strings.Contains(result, want) {
+		t.Errorf("Expected output to contain %q, got: %s", want, result)
+	}
+}
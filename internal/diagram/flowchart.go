@@ -1,49 +1,176 @@
 package diagram
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/TyphonHill/go-mermaid/diagrams/flowchart"
+	"github.com/TyphonHill/go-mermaid/diagrams/utils/basediagram"
 	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
 )
 
 // GenerateMermaidFlowchart generates a Mermaid flowchart (TD) from a UsesNode tree using go-mermaid.
 func GenerateMermaidFlowchart(root *github.UsesNode) string {
+	return generateMermaidFlowchart(flattenTree(root), DefaultTheme(), false)
+}
+
+// generateMermaidFlowchart generates a Mermaid flowchart (TD) from a flattened dependency graph. Nodes are
+// drawn with the shape and the class of their style kind in the theme, and a legend of the kinds in use is
+// added when legend is true.
+func generateMermaidFlowchart(g *flatGraph, theme *Theme, legend bool) string {
 	fc := flowchart.NewFlowchart()
 	fc.Title = "Workflow Graph"
+	if theme.Mermaid.Theme != "" {
+		fc.Config.SetTheme(basediagram.ThemeName(theme.Mermaid.Theme))
+	}
 
-	nodeMap := make(map[string]*flowchart.Node)
-	buildFlowchartNodes(fc, root, nodeMap)
-	addFlowchartLinks(fc, root, nodeMap)
+	nodeMap := make(map[string]*flowchart.Node, len(g.Nodes))
+	var cycleLinks []string
+	classes := make(map[string]*flowchart.Class)
+	var used []string
+	for _, n := range g.Nodes {
+		node := fc.AddNode(flowchartText(n.Lines))
+		style := flowchartStyle(n)
+		if shape, ok := flowchartShapes[theme.Styles[n.Style].Shape]; ok {
+			node.SetShape(shape)
+		}
+		if _, ok := classes[style]; !ok {
+			used = append(used, style)
+		}
+		node.SetClass(flowchartClass(fc, theme, style, classes))
+		nodeMap[n.ID] = node
+	}
+	var legendNodes []string
+	if legend {
+		legendNodes = flowchartLegend(fc, theme, used, classes)
+	}
+	for i, e := range g.Edges {
+		link := fc.AddLink(nodeMap[e.From], nodeMap[e.To])
+		switch e.Kind {
+		case graph.EdgeTypeNeeds:
+			link.SetShape(flowchart.LinkShapeDotted).SetText("needs")
+		case graph.EdgeTypeUses:
+			link.SetShape(flowchart.LinkShapeThick).SetText("uses")
+		case graph.EdgeTypeCycle:
+			link.SetShape(flowchart.LinkShapeThick).SetText("cycle")
+			cycleLinks = append(cycleLinks, strconv.Itoa(i))
+		}
+	}
 
-	return fc.String()
+	output := withInitDirective(fc.String(), theme.initDirective())
+	// go-mermaid has no link styles: cycle back-edges are highlighted by their index, links being rendered last.
+	if len(cycleLinks) > 0 {
+		output += "    linkStyle " + strings.Join(cycleLinks, ",") + " " + flowchartCycleLinkStyle + "\n"
+	}
+	output += flowchartSubgraphs(g, nodeMap)
+	if len(legendNodes) > 0 {
+		output += "    subgraph legend [Legend]\n        " + strings.Join(legendNodes, "\n        ") + "\n    end\n"
+	}
+	return output
+}
+
+// flowchartStyle returns the class of a node: the unresolved classes for the references that could not be
+// resolved, or its style kind.
+func flowchartStyle(n graphNode) string {
+	switch {
+	case n.Unresolved == github.UnresolvedDepthLimit:
+		return StyleDepthLimit
+	case n.Unresolved != "":
+		return StyleUnresolved
+	}
+	return n.Style
 }
 
-// buildFlowchartNodes recursively adds nodes to the flowchart.
-func buildFlowchartNodes(fc *flowchart.Flowchart, node *github.UsesNode, nodeMap map[string]*flowchart.Node) {
-	if node == nil {
-		return
+// flowchartLegend adds a node for each of the used classes, in legend order, drawn like the nodes of that
+// class, and returns their IDs.
+func flowchartLegend(fc *flowchart.Flowchart, theme *Theme, used []string, classes map[string]*flowchart.Class) []string {
+	inUse := make(map[string]bool, len(used))
+	for _, style := range used {
+		inUse[style] = true
 	}
-	if _, exists := nodeMap[node.UniqueID]; !exists {
-		n := fc.AddNode(node.UniqueID)
-		n.Text = node.Name
+	var ids []string
+	for _, entry := range styleLabels {
+		if !inUse[entry.kind] {
+			continue
+		}
+		node := fc.AddNode(entry.label)
+		if shape, ok := flowchartShapes[theme.Styles[entry.kind].Shape]; ok {
+			node.SetShape(shape)
+		}
+		node.SetClass(flowchartClass(fc, theme, entry.kind, classes))
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
 
-		nodeMap[node.UniqueID] = n
+// withInitDirective inserts the %%{init}%% directive, if any, between the front matter and the diagram.
+func withInitDirective(output, directive string) string {
+	const separator = "---\n"
+	if directive == "" || !strings.HasPrefix(output, separator) {
+		return output
 	}
-	for _, child := range node.Children {
-		buildFlowchartNodes(fc, child, nodeMap)
+	end := strings.Index(output[len(separator):], separator)
+	if end < 0 {
+		return output
 	}
+	end += 2 * len(separator)
+	return output[:end] + directive + output[end:]
 }
 
-// addFlowchartLinks recursively adds links between nodes to the flowchart.
-func addFlowchartLinks(fc *flowchart.Flowchart, node *github.UsesNode, nodeMap map[string]*flowchart.Node) {
-	if node == nil {
-		return
+// flowchartSubgraphs returns the subgraphs of the groups of g, nested like the groups. go-mermaid subgraphs
+// only hold links, so the subgraphs are written after the diagram and list the IDs of their nodes, which
+// moves the nodes already declared into them.
+func flowchartSubgraphs(g *flatGraph, nodeMap map[string]*flowchart.Node) string {
+	members := make(map[string][]string)
+	for _, n := range g.Nodes {
+		if n.Group != "" {
+			members[n.Group] = append(members[n.Group], nodeMap[n.ID].ID)
+		}
+	}
+	children := make(map[string][]graphGroup)
+	for _, group := range g.Groups {
+		children[group.Parent] = append(children[group.Parent], group)
 	}
-	from := nodeMap[node.UniqueID]
-	for _, child := range node.Children {
-		to := nodeMap[child.UniqueID]
-		if from != nil && to != nil {
-			fc.AddLink(from, to)
+
+	var sb strings.Builder
+	var write func(group graphGroup, indent string)
+	write = func(group graphGroup, indent string) {
+		title := flowchartText(group.Title)
+		fmt.Fprintf(&sb, "%ssubgraph s%s [\"%s\"]\n", indent, group.ID, title)
+		for _, id := range members[group.ID] {
+			sb.WriteString(indent + "    " + id + "\n")
+		}
+		for _, child := range children[group.ID] {
+			write(child, indent+"    ")
 		}
-		addFlowchartLinks(fc, child, nodeMap)
+		sb.WriteString(indent + "end\n")
+	}
+	for _, group := range children[""] {
+		write(group, "    ")
+	}
+	return sb.String()
+}
+
+// flowchartText returns the lines of a node label or subgraph title as Mermaid text: labels are written
+// between double quotes, so the quotes of step names and scripts are escaped as entity codes.
+func flowchartText(lines []string) string {
+	return strings.ReplaceAll(strings.Join(lines, "<br/>"), `"`, "#quot;")
+}
+
+// flowchartCycleLinkStyle is the style of the back-edges closing a reference cycle.
+const flowchartCycleLinkStyle = "stroke:#dc2626,stroke-width:3px,color:#dc2626"
+
+// flowchartClass returns the class of the given style kind, defining it with the style of the theme the
+// first time it is used.
+func flowchartClass(fc *flowchart.Flowchart, theme *Theme, kind string, classes map[string]*flowchart.Class) *flowchart.Class {
+	class, ok := classes[kind]
+	if !ok {
+		style := theme.Styles[kind]
+		class = fc.AddClass(kind)
+		class.Style = &flowchart.NodeStyle{Color: style.Color, Fill: style.Fill, Stroke: style.Stroke, StrokeWidth: style.StrokeWidth, StrokeDash: style.StrokeDash}
+		classes[kind] = class
 	}
+	return class
 }
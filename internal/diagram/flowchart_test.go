@@ -1,10 +1,12 @@
 package diagram
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
 )
 
 func TestGenerateMermaidFlowchart_Simple(t *testing.T) {
@@ -25,3 +27,188 @@ func TestGenerateMermaidFlowchart_Simple(t *testing.T) {
 		t.Errorf("Expected output to contain all node names, got: %s", result)
 	}
 }
+
+func TestGenerateMermaidFlowchart_Needs(t *testing.T) {
+	root := &github.UsesNode{
+		Name:     "root",
+		UniqueID: "root",
+		Children: []*github.UsesNode{
+			{Name: "build", UniqueID: "root/build"},
+			{Name: "deploy", UniqueID: "root/deploy", Needs: []string{"root/build"}},
+		},
+	}
+
+	result := GenerateMermaidFlowchart(root)
+	if !strings.Contains(result, "1 -.->|needs| 2") {
+		t.Errorf("Expected a needs link from build to deploy, got: %s", result)
+	}
+}
+
+func TestGenerateMermaidFlowchart_Triggers(t *testing.T) {
+	root := &github.UsesNode{
+		Name:     "root",
+		UniqueID: "root",
+		Triggers: github.Triggers{
+			{Event: "push", Branches: []string{"main"}},
+			{Event: "workflow_dispatch"},
+		},
+	}
+
+	result := GenerateMermaidFlowchart(root)
+	if !strings.Contains(result, `1@{ shape: stadium, label: "push<br/>branches: main"}`) {
+		t.Errorf("Expected a push trigger node, got: %s", result)
+	}
+	if !strings.Contains(result, `2@{ shape: stadium, label: "workflow_dispatch"}`) {
+		t.Errorf("Expected a workflow_dispatch trigger node, got: %s", result)
+	}
+	if !strings.Contains(result, "1 --> 0") || !strings.Contains(result, "2 --> 0") {
+		t.Errorf("Expected trigger links to the root node, got: %s", result)
+	}
+}
+
+func TestGenerateMermaidFlowchart_Calls(t *testing.T) {
+	root := &github.UsesNode{
+		Name:     "repo",
+		UniqueID: "repo",
+		Children: []*github.UsesNode{
+			{Name: "ci.yml", UniqueID: "ci.yml", Children: []*github.UsesNode{
+				{Name: "test", UniqueID: "ci.yml/test", Calls: []string{"build.yml"}},
+			}},
+			{Name: "build.yml", UniqueID: "build.yml"},
+		},
+	}
+
+	result := GenerateMermaidFlowchart(root)
+	if !strings.Contains(result, "2 ==>|uses| 3") {
+		t.Errorf("Expected a uses link from the caller job to the called workflow, got: %s", result)
+	}
+}
+
+func TestGenerateMermaidFlowchart_Unresolved(t *testing.T) {
+	root := &github.UsesNode{Name: "root", UniqueID: "root", Children: []*github.UsesNode{
+		{Name: "call", UniqueID: "root/call", Uses: "./missing.yml", Unresolved: &github.ResolveError{Reason: github.UnresolvedAuth}},
+		{Name: "build", UniqueID: "root/build"},
+	}}
+
+	result := GenerateMermaidFlowchart(root)
+	for _, want := range []string{
+		"classDef unresolved color:#b91c1c,fill:#fee2e2,stroke:#b91c1c,stroke-width:2,stroke-dasharray:5 5",
+		"call<br/>unresolved: unauthorized",
+		":::unresolved",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+	if strings.Contains(result, "classDef depthLimit") {
+		t.Errorf("Expected unused classes not to be defined, got: %s", result)
+	}
+}
+
+func TestGenerateMermaidFlowchart_Cycle(t *testing.T) {
+	root := &github.UsesNode{Name: "root", UniqueID: "root", Children: []*github.UsesNode{
+		{Name: "a", UniqueID: "root/a", Uses: "./a.yml", Children: []*github.UsesNode{
+			{Name: "back", UniqueID: "root/a/back", Uses: "./a.yml", Cycle: []string{"./a.yml", "./a.yml"}, CycleTo: "root/a"},
+		}},
+	}}
+
+	result := GenerateMermaidFlowchart(root)
+	for _, want := range []string{
+		"2 ==>|cycle| 1",
+		"linkStyle 2 " + flowchartCycleLinkStyle,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+}
+
+func TestGenerateMermaidFlowchart_Subgraphs(t *testing.T) {
+	result := generateMermaidFlowchart(flatten(graph.FromTree(referenceTree()), newOptions()), DefaultTheme(), false)
+	want := `    subgraph sg0 ["ci<br/>ci.yml"]
+        0
+        1
+        5
+        subgraph sg1 ["./.github/workflows/build.yml<br/>build.yml"]
+            2
+            3
+            subgraph sg2 ["./setup<br/>setup/action.yml"]
+                4
+            end
+        end
+        subgraph sg3 ["./.github/workflows/build.yml<br/>build.yml"]
+            6
+            7
+            subgraph sg4 ["./setup<br/>setup/action.yml"]
+                8
+            end
+        end
+    end
+`
+	if !strings.HasSuffix(result, want) {
+		t.Errorf("Expected nested subgraphs for the workflows and actions, got: %s", result)
+	}
+	if !strings.Contains(result, "1 ==>|uses| 2") {
+		t.Errorf("Expected a uses link from the caller job into the called workflow, got: %s", result)
+	}
+}
+
+func TestGenerateMermaidFlowchart_Styles(t *testing.T) {
+	result := GenerateMermaidFlowchart(referenceTree())
+	for _, want := range []string{
+		"classDef workflow color:#1e3a8a,fill:#dbeafe,stroke:#1d4ed8",
+		`0@{ shape: doc, label: "ci"}:::workflow`,
+		`1@{ shape: fr-rect, label: "a"}:::reusableWorkflow`,
+		`2@{ shape: rect, label: "compile"}:::job`,
+		`3@{ shape: hex, label: "./setup"}:::localAction`,
+		`4@{ shape: hex, label: "actions/checkout@v4"}:::firstPartyAction`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+	if strings.Contains(result, "classDef trigger") || strings.Contains(result, "subgraph legend") {
+		t.Errorf("Expected no unused classes and no legend, got: %s", result)
+	}
+}
+
+func TestGenerateMermaidFlowchart_ThemeAndLegend(t *testing.T) {
+	theme, err := ParseTheme([]byte("mermaid:\n  theme: dark\n  init:\n    flowchart:\n      curve: basis\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := generateMermaidFlowchart(flattenTree(referenceTree()), theme, true)
+	for _, want := range []string{
+		"    theme: dark\n",
+		"---\n%%{init: {\"flowchart\":{\"curve\":\"basis\"}}}%%\nflowchart TB\n",
+		`9@{ shape: doc, label: "workflow"}:::workflow`,
+		`10@{ shape: rect, label: "job"}:::job`,
+		`11@{ shape: fr-rect, label: "reusable workflow call"}:::reusableWorkflow`,
+		`12@{ shape: hex, label: "first-party action"}:::firstPartyAction`,
+		`13@{ shape: hex, label: "local action"}:::localAction`,
+		"    subgraph legend [Legend]\n        9\n        10\n        11\n        12\n        13\n    end\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+}
+
+func TestGenerateMermaidFlowchart_QuotedLabels(t *testing.T) {
+	wf := &github.Workflow{Jobs: map[string]github.Job{"build": {Steps: []github.Step{
+		{Run: `echo "hello [world]" | tee x`},
+		{Name: `Say "hi"`, Run: "echo hi"},
+	}}}}
+	tree := github.BuildUsesTree(context.Background(), "root", wf, nil, 2).WithSteps()
+	result := generateMermaidFlowchart(flatten(graph.FromTree(tree), newOptions()), DefaultTheme(), false)
+	for _, want := range []string{`echo #quot;hello [world]#quot; | tee x`, `Say #quot;hi#quot;`} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+	for _, unwanted := range []string{`"hello`, `"hi"`} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("Expected the quotes of %q to be escaped, got: %s", unwanted, result)
+		}
+	}
+}
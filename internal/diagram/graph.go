@@ -0,0 +1,189 @@
+package diagram
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
+)
+
+// graphNode is a node of the dependency graph with a renderer friendly ID.
+type graphNode struct {
+	ID      string
+	Lines   []string // label lines; the first one is the node name
+	Trigger bool
+	Style   string // style kind of the node, one of the Style constants
+	// Unresolved is the reason why the node reference could not be resolved, if any.
+	Unresolved string
+	Group      string // ID of the innermost group of the node, "" for nodes outside of any group
+}
+
+// graphGroup is a workflow or composite action whose nodes are drawn together.
+type graphGroup struct {
+	ID     string   // g0, g1, ...
+	Title  []string // title lines: the name of the workflow or action (or its reference), and its source
+	Parent string   // ID of the enclosing group, "" for top level groups
+}
+
+// graphEdge is a link between two nodes of the dependency graph, using renderer friendly IDs.
+type graphEdge struct {
+	From string
+	To   string
+	Kind string // one of the graph.EdgeType constants
+}
+
+// flatGraph is the dependency graph with IDs (n0, n1, ...) that are safe to use in any diagram language.
+// Groups are listed before the groups they enclose.
+type flatGraph struct {
+	Nodes  []graphNode
+	Edges  []graphEdge
+	Groups []graphGroup
+}
+
+// flattenTree converts the tree into a flatGraph, keeping the node and edge order of graph.FromTree.
+func flattenTree(root *github.UsesNode) *flatGraph {
+	return flatten(graph.FromTree(root), newOptions())
+}
+
+// flatten converts the dependency graph of the view and detail level of o into a flatGraph, keeping its
+// node and edge order. The attributes of the nodes are added to their labels with graph.DetailFull.
+//
+// Steps are drawn as a chain: a step run after another one is only linked to it, not to its job or action.
+func flatten(dg *graph.Graph, o *options) *flatGraph {
+	g := &flatGraph{}
+	ids := make(map[string]string, len(dg.Nodes))
+	groups := g.group(dg, o.view == graph.ViewDAG)
+	for i, n := range dg.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		lines := append([]string{n.Name}, n.Details...)
+		if o.detail == graph.DetailFull {
+			lines = append(lines, attributeLines(n.Attributes)...)
+		}
+		g.Nodes = append(g.Nodes, graphNode{
+			ID:         ids[n.ID],
+			Lines:      lines,
+			Trigger:    n.Type == graph.NodeTypeTrigger,
+			Style:      styleOf(n),
+			Unresolved: n.Unresolved,
+			Group:      groups[n.ID],
+		})
+	}
+	chained := make(map[string]bool)
+	for _, e := range dg.Edges {
+		if e.Type == graph.EdgeTypeNext {
+			chained[e.To] = true
+		}
+	}
+	for _, e := range dg.Edges {
+		if (e.Type == graph.EdgeTypeContains || e.Type == graph.EdgeTypeUses) && chained[e.To] {
+			continue
+		}
+		g.Edges = append(g.Edges, graphEdge{From: ids[e.From], To: ids[e.To], Kind: e.Type})
+	}
+	return g
+}
+
+// group adds a group for each workflow and each expanded composite action of the dependency graph, and
+// returns the ID of the group of each node, by graph node ID.
+//
+// The nodes of the graph are listed depth first and the parent of a node is the source of the first
+// contains or uses edge pointing at it. Workflow nodes open a group holding themselves and their contents.
+// In the tree view, the nodes a job or an action uses (the jobs of the reusable workflow or the steps of
+// the composite action) are held by a group of their caller; in the DAG view, a referenced workflow or
+// action is a single node, which opens a group when it has contents. Groups are nested in the group of the
+// parent of their first node.
+func (g *flatGraph) group(dg *graph.Graph, dag bool) map[string]string {
+	parents := make(map[string]graph.Edge, len(dg.Nodes))
+	contents := make(map[string]bool)
+	for _, e := range dg.Edges {
+		if e.Type != graph.EdgeTypeContains && e.Type != graph.EdgeTypeUses {
+			continue
+		}
+		if _, ok := parents[e.To]; !ok {
+			parents[e.To] = e
+			contents[e.From] = true
+		}
+	}
+
+	nodes := make(map[string]graph.Node, len(dg.Nodes))
+	groups := make(map[string]string, len(dg.Nodes))
+	callers := make(map[string]string)
+	add := func(parent string, n graph.Node, name string) string {
+		id := fmt.Sprintf("g%d", len(g.Groups))
+		if n.Title != "" {
+			name = n.Title
+		}
+		title := []string{name}
+		if n.URL != "" && n.URL != name {
+			title = append(title, n.URL)
+		}
+		g.Groups = append(g.Groups, graphGroup{ID: id, Title: title, Parent: parent})
+		return id
+	}
+	for _, n := range dg.Nodes {
+		nodes[n.ID] = n
+		parent, ok := parents[n.ID]
+		outer := ""
+		if ok {
+			outer = groups[parent.From]
+		}
+		switch {
+		case n.Type == graph.NodeTypeWorkflow || (dag && ok && parent.Type == graph.EdgeTypeUses && contents[n.ID]):
+			groups[n.ID] = add(outer, n, n.Name)
+		case !dag && ok && parent.Type == graph.EdgeTypeUses:
+			if _, ok := callers[parent.From]; !ok {
+				caller := nodes[parent.From]
+				callers[parent.From] = add(outer, caller, caller.Uses)
+			}
+			groups[n.ID] = callers[parent.From]
+		default:
+			groups[n.ID] = outer
+		}
+	}
+	return groups
+}
+
+// styleOf returns the style kind of a node of the dependency graph.
+func styleOf(n graph.Node) string {
+	switch {
+	case n.Type == graph.NodeTypeTrigger:
+		return StyleTrigger
+	case n.Type == graph.NodeTypeRepository:
+		return StyleRepository
+	case n.Type == graph.NodeTypeWorkflow:
+		return StyleWorkflow
+	case n.Type == graph.NodeTypeJob && n.Uses != "":
+		return StyleReusableWorkflow
+	case n.Type == graph.NodeTypeJob:
+		return StyleJob
+	case n.Uses == "":
+		return StyleStep
+	}
+
+	refType, owner := "", ""
+	if n.Ref != nil {
+		refType, owner = n.Ref.Type, n.Ref.Owner
+	} else if ref, err := github.ParseActionRef(n.Uses, github.Repository{}); err == nil {
+		refType, owner = ref.Type, ref.Owner
+	}
+	switch {
+	case refType == github.UsesTypeDocker:
+		return StyleDockerAction
+	case refType == github.UsesTypeLocal:
+		return StyleLocalAction
+	case owner == "actions" || owner == "github":
+		return StyleFirstPartyAction
+	}
+	return StyleThirdPartyAction
+}
+
+// attributeLines returns the 'key: value' label lines of the attributes, sorted by key.
+func attributeLines(attrs map[string]string) []string {
+	lines := make([]string, 0, len(attrs))
+	for key, value := range attrs {
+		lines = append(lines, key+": "+value)
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,134 @@
+package diagram
+
+import (
+	"context"
+	"testing"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
+	"github.com/stretchr/testify/assert"
+)
+
+// sampleTree returns a tree exercising every kind of edge.
+func sampleTree() *github.UsesNode {
+	return &github.UsesNode{
+		Name:     "root",
+		UniqueID: "root",
+		Triggers: github.Triggers{{Event: "push", Branches: []string{"main"}}},
+		Children: []*github.UsesNode{
+			{Name: "build", UniqueID: "root/build"},
+			{Name: "deploy", UniqueID: "root/deploy", Needs: []string{"root/build"}, Calls: []string{"shared.yml"}},
+			{Name: "shared.yml", UniqueID: "shared.yml"},
+		},
+	}
+}
+
+func TestFlattenTree(t *testing.T) {
+	g := flattenTree(sampleTree())
+	assert.Equal(t, []graphNode{
+		{ID: "n0", Lines: []string{"root"}, Style: StyleWorkflow, Group: "g0"},
+		{ID: "n1", Lines: []string{"build"}, Style: StyleJob, Group: "g0"},
+		{ID: "n2", Lines: []string{"deploy"}, Style: StyleJob, Group: "g0"},
+		{ID: "n3", Lines: []string{"shared.yml"}, Style: StyleJob, Group: "g0"},
+		{ID: "n4", Lines: []string{"push", "branches: main"}, Trigger: true, Style: StyleTrigger},
+	}, g.Nodes)
+	assert.Equal(t, []graphEdge{
+		{From: "n0", To: "n1", Kind: graph.EdgeTypeContains},
+		{From: "n0", To: "n2", Kind: graph.EdgeTypeContains},
+		{From: "n0", To: "n3", Kind: graph.EdgeTypeContains},
+		{From: "n1", To: "n2", Kind: graph.EdgeTypeNeeds},
+		{From: "n2", To: "n3", Kind: graph.EdgeTypeUses},
+		{From: "n4", To: "n0", Kind: graph.EdgeTypeTriggers},
+	}, g.Edges)
+	assert.Equal(t, []graphGroup{{ID: "g0", Title: []string{"root"}}}, g.Groups)
+}
+
+// referenceTree returns a workflow whose jobs call a reusable workflow and use a composite action.
+func referenceTree() *github.UsesNode {
+	call := func(name string) *github.UsesNode {
+		return &github.UsesNode{Name: name, UniqueID: "ci.yml/" + name, Kind: github.NodeKindJob, Uses: "./.github/workflows/build.yml", URL: "build.yml",
+			Children: []*github.UsesNode{
+				{Name: "compile", UniqueID: "ci.yml/" + name + "/compile", Kind: github.NodeKindJob, Children: []*github.UsesNode{
+					{Name: "./setup", UniqueID: "ci.yml/" + name + "/compile/./setup", Kind: github.NodeKindAction, Uses: "./setup", URL: "setup/action.yml",
+						Children: []*github.UsesNode{{Name: "actions/checkout@v4", UniqueID: "ci.yml/" + name + "/compile/./setup/actions/checkout@v4",
+							Kind: github.NodeKindAction, Uses: "actions/checkout@v4"}}},
+				}},
+			}}
+	}
+	return &github.UsesNode{Name: "ci", UniqueID: "ci.yml", Kind: github.NodeKindWorkflow, URL: "ci.yml", Children: []*github.UsesNode{call("a"), call("b")}}
+}
+
+func TestFlatten_GroupTitles(t *testing.T) {
+	ci, err := github.ParseWorkflowYAML("ci.yml", []byte("name: CI\njobs:\n  call:\n    uses: ./.github/workflows/build.yml\n"))
+	assert.NoError(t, err)
+	fetcher := func(ctx context.Context, uses string) (*github.Workflow, error) {
+		if uses == "./setup" {
+			action, err := github.ParseActionYAML([]byte("name: Setup\nruns:\n  using: composite\n  steps:\n    - uses: actions/checkout@v4\n"))
+			return &github.Workflow{URL: "setup/action.yml", Action: action}, err
+		}
+		return github.ParseWorkflowYAML("build.yml", []byte("name: Build\njobs:\n  compile:\n    steps:\n      - uses: ./setup\n"))
+	}
+	tree := github.BuildUsesTree(context.Background(), "workflow", ci, fetcher, 3)
+
+	want := []graphGroup{
+		{ID: "g0", Title: []string{"CI", "ci.yml"}},
+		{ID: "g1", Title: []string{"Build", "build.yml"}, Parent: "g0"},
+		{ID: "g2", Title: []string{"Setup", "setup/action.yml"}, Parent: "g1"},
+	}
+	assert.Equal(t, want, flatten(graph.FromTree(tree), newOptions()).Groups)
+	assert.Equal(t, want, flatten(graph.FromDAG(tree), newOptions(WithView(graph.ViewDAG))).Groups)
+}
+
+func TestFlatten_Groups(t *testing.T) {
+	g := flatten(graph.FromTree(referenceTree()), newOptions())
+	assert.Equal(t, []graphGroup{
+		{ID: "g0", Title: []string{"ci", "ci.yml"}},
+		{ID: "g1", Title: []string{"./.github/workflows/build.yml", "build.yml"}, Parent: "g0"},
+		{ID: "g2", Title: []string{"./setup", "setup/action.yml"}, Parent: "g1"},
+		{ID: "g3", Title: []string{"./.github/workflows/build.yml", "build.yml"}, Parent: "g0"},
+		{ID: "g4", Title: []string{"./setup", "setup/action.yml"}, Parent: "g3"},
+	}, g.Groups)
+	groups := make([]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		groups[i] = n.Group
+	}
+	// ci, a, compile, ./setup, actions/checkout@v4, b, compile, ./setup, actions/checkout@v4
+	assert.Equal(t, []string{"g0", "g0", "g1", "g1", "g2", "g0", "g3", "g3", "g4"}, groups)
+
+	g = flatten(graph.FromDAG(referenceTree()), newOptions(WithView(graph.ViewDAG)))
+	assert.Equal(t, []graphGroup{
+		{ID: "g0", Title: []string{"ci", "ci.yml"}},
+		{ID: "g1", Title: []string{"./.github/workflows/build.yml", "build.yml"}, Parent: "g0"},
+		{ID: "g2", Title: []string{"./setup", "setup/action.yml"}, Parent: "g1"},
+	}, g.Groups)
+	groups = make([]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		groups[i] = n.Group
+	}
+	// ci, a, ./.github/workflows/build.yml, compile, ./setup, actions/checkout@v4, b
+	assert.Equal(t, []string{"g0", "g0", "g1", "g1", "g2", "g2", "g0"}, groups)
+}
+
+func TestStyleOf(t *testing.T) {
+	cases := []struct {
+		node graph.Node
+		want string
+	}{
+		{node: graph.Node{Type: graph.NodeTypeRepository}, want: StyleRepository},
+		{node: graph.Node{Type: graph.NodeTypeWorkflow}, want: StyleWorkflow},
+		{node: graph.Node{Type: graph.NodeTypeTrigger}, want: StyleTrigger},
+		{node: graph.Node{Type: graph.NodeTypeJob}, want: StyleJob},
+		{node: graph.Node{Type: graph.NodeTypeJob, Uses: "./.github/workflows/build.yml"}, want: StyleReusableWorkflow},
+		{node: graph.Node{Type: graph.NodeTypeStep}, want: StyleStep},
+		{node: graph.Node{Type: graph.NodeTypeAction, Uses: "actions/checkout@v4"}, want: StyleFirstPartyAction},
+		{node: graph.Node{Type: graph.NodeTypeStep, Uses: "github/codeql-action/init@v3"}, want: StyleFirstPartyAction},
+		{node: graph.Node{Type: graph.NodeTypeAction, Uses: "docker/login-action@v3"}, want: StyleThirdPartyAction},
+		{node: graph.Node{Type: graph.NodeTypeAction, Uses: "docker://alpine:3.20"}, want: StyleDockerAction},
+		{node: graph.Node{Type: graph.NodeTypeAction, Uses: "./.github/actions/setup"}, want: StyleLocalAction},
+		{node: graph.Node{Type: graph.NodeTypeAction, Uses: "setup", Ref: &graph.Ref{Type: github.UsesTypeLocal}}, want: StyleLocalAction},
+		{node: graph.Node{Type: graph.NodeTypeAction, Uses: "${{ matrix.action }}"}, want: StyleThirdPartyAction},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, styleOf(c.node), c.node.Uses)
+	}
+}
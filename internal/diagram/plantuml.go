@@ -0,0 +1,66 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
+)
+
+// plantUMLArrows maps edge kinds to PlantUML arrows.
+var plantUMLArrows = map[string]string{
+	graph.EdgeTypeContains: "-->",
+	graph.EdgeTypeNeeds:    "..>",
+	graph.EdgeTypeUses:     "-[bold]->",
+	graph.EdgeTypeTriggers: "-->",
+	graph.EdgeTypeCycle:    "-[#red,bold]->",
+	graph.EdgeTypeNext:     "-->",
+}
+
+// GeneratePlantUML generates a PlantUML diagram from a UsesNode tree.
+func GeneratePlantUML(root *github.UsesNode) string {
+	return generatePlantUML(flattenTree(root))
+}
+
+// generatePlantUML generates a PlantUML diagram from a flattened dependency graph.
+func generatePlantUML(g *flatGraph) string {
+	var sb strings.Builder
+	sb.WriteString("@startuml\n")
+	sb.WriteString("title Workflow Graph\n")
+	for _, n := range g.Nodes {
+		element := "rectangle"
+		if n.Trigger {
+			element = "usecase"
+		}
+		fmt.Fprintf(&sb, "%s %s as %s%s\n", element, plantUMLQuote(strings.Join(n.Lines, "\n")), n.ID, plantUMLUnresolvedStyle(n.Unresolved))
+	}
+	for _, e := range g.Edges {
+		label := ""
+		if e.Kind == graph.EdgeTypeNeeds || e.Kind == graph.EdgeTypeUses || e.Kind == graph.EdgeTypeCycle {
+			label = " : " + e.Kind
+		}
+		fmt.Fprintf(&sb, "%s %s %s%s\n", e.From, plantUMLArrows[e.Kind], e.To, label)
+	}
+	sb.WriteString("@enduml\n")
+	return sb.String()
+}
+
+// plantUMLUnresolvedStyle returns the inline style of a node whose reference could not be resolved for the given reason.
+func plantUMLUnresolvedStyle(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case github.UnresolvedDepthLimit:
+		return " #line.dashed;line:gray"
+	default:
+		return " #line.dashed;line:red;text:red"
+	}
+}
+
+// plantUMLQuote returns s as a PlantUML quoted string.
+func plantUMLQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, `'`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,24 @@
+package diagram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePlantUML(t *testing.T) {
+	result := GeneratePlantUML(sampleTree())
+	for _, want := range []string{
+		"@startuml",
+		`rectangle "root" as n0`,
+		`usecase "push\nbranches: main" as n4`,
+		"n0 --> n1",
+		"n1 ..> n2 : needs",
+		"n2 -[bold]-> n3 : uses",
+		"n4 --> n0",
+		"@enduml",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, result)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package diagram
+
+import (
+	"fmt"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
+)
+
+// Supported output formats.
+const (
+	FormatMermaid  = "mermaid"
+	FormatDOT      = "dot"
+	FormatPlantUML = "plantuml"
+	FormatD2       = "d2"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+)
+
+// Renderer renders a UsesNode tree into a diagram or an export format.
+type Renderer interface {
+	Render(root *github.UsesNode) (string, error)
+}
+
+// RendererFunc adapts a function to the Renderer interface.
+type RendererFunc func(root *github.UsesNode) (string, error)
+
+// Render calls f(root).
+func (f RendererFunc) Render(root *github.UsesNode) (string, error) {
+	return f(root)
+}
+
+// Option configures a Renderer.
+type Option func(*options)
+
+// options are the settings shared by the renderers.
+type options struct {
+	view   string
+	detail string
+	theme  *Theme
+	legend bool
+}
+
+// newOptions returns the default options, modified by opts.
+func newOptions(opts ...Option) *options {
+	o := &options{view: graph.ViewTree, detail: graph.DetailJobs, theme: DefaultTheme()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithView selects the view of the dependency graph that is rendered: graph.ViewTree (the default), where
+// every reference is listed under each of its callers, or graph.ViewDAG, with one node per unique reference.
+func WithView(view string) Option {
+	return func(o *options) {
+		o.view = view
+	}
+}
+
+// WithDetail selects the detail level of the rendered graph: graph.DetailJobs (the default), where only the
+// steps using an action are listed, graph.DetailSteps, where every step is listed, or graph.DetailFull,
+// where every step is listed and the diagrams show the attributes of the nodes.
+func WithDetail(detail string) Option {
+	return func(o *options) {
+		o.detail = detail
+	}
+}
+
+// WithTheme selects the theme of the Mermaid flowcharts (DefaultTheme by default).
+func WithTheme(theme *Theme) Option {
+	return func(o *options) {
+		if theme != nil {
+			o.theme = theme
+		}
+	}
+}
+
+// WithLegend adds a legend of the kinds of nodes to the Mermaid flowcharts.
+func WithLegend(legend bool) Option {
+	return func(o *options) {
+		o.legend = legend
+	}
+}
+
+// graph converts the tree into the dependency graph of the selected view and detail level.
+func (o *options) graph(root *github.UsesNode) *graph.Graph {
+	if o.detail != graph.DetailJobs {
+		root = root.WithSteps()
+	}
+	if o.view == graph.ViewDAG {
+		return graph.FromDAG(root)
+	}
+	return graph.FromTree(root)
+}
+
+// graphRenderer adapts a generator of flattened dependency graphs to the Renderer interface.
+func graphRenderer(o *options, generate func(g *flatGraph) string) Renderer {
+	return RendererFunc(func(root *github.UsesNode) (string, error) {
+		return generate(flatten(o.graph(root), o)), nil
+	})
+}
+
+// NewRenderer returns the renderer for the given format and diagram type.
+// The diagram type (flowchart or sequence) only applies to the Mermaid format;
+// the other formats always render the dependency graph.
+func NewRenderer(format, diagramType string, opts ...Option) (Renderer, error) {
+	switch format {
+	case "", FormatMermaid, FormatDOT, FormatPlantUML, FormatD2, FormatJSON, FormatYAML:
+	default:
+		return nil, fmt.Errorf("invalid format: %s", format)
+	}
+	o := newOptions(opts...)
+	if o.view != graph.ViewTree && o.view != graph.ViewDAG {
+		return nil, fmt.Errorf("invalid view: %s", o.view)
+	}
+	if o.detail != graph.DetailJobs && o.detail != graph.DetailSteps && o.detail != graph.DetailFull {
+		return nil, fmt.Errorf("invalid detail: %s", o.detail)
+	}
+
+	if format == FormatMermaid || format == "" {
+		switch diagramType {
+		case "sequence":
+			return graphRenderer(o, generateMermaidSequence), nil
+		case "flowchart":
+			return graphRenderer(o, func(g *flatGraph) string {
+				return generateMermaidFlowchart(g, o.theme, o.legend)
+			}), nil
+		default:
+			return nil, fmt.Errorf("invalid diagram type: %s", diagramType)
+		}
+	}
+
+	if diagramType != "flowchart" {
+		return nil, fmt.Errorf("diagram type %s is not supported by the %s format", diagramType, format)
+	}
+	switch format {
+	case FormatDOT:
+		return graphRenderer(o, generateDOT), nil
+	case FormatPlantUML:
+		return graphRenderer(o, generatePlantUML), nil
+	case FormatD2:
+		return graphRenderer(o, generateD2), nil
+	case FormatJSON:
+		return RendererFunc(func(root *github.UsesNode) (string, error) {
+			return o.graph(root).JSON()
+		}), nil
+	case FormatYAML:
+		return RendererFunc(func(root *github.UsesNode) (string, error) {
+			return o.graph(root).YAML()
+		}), nil
+	}
+	return nil, fmt.Errorf("invalid format: %s", format)
+}
@@ -0,0 +1,138 @@
+package diagram
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRenderer(t *testing.T) {
+	cases := []struct {
+		format      string
+		diagramType string
+		contains    string
+	}{
+		{format: "", diagramType: "flowchart", contains: "flowchart TB"},
+		{format: FormatMermaid, diagramType: "sequence", contains: "sequenceDiagram"},
+		{format: FormatDOT, diagramType: "flowchart", contains: "digraph workflow"},
+		{format: FormatPlantUML, diagramType: "flowchart", contains: "@startuml"},
+		{format: FormatD2, diagramType: "flowchart", contains: "direction: down"},
+	}
+	for _, c := range cases {
+		renderer, err := NewRenderer(c.format, c.diagramType)
+		assert.NoError(t, err, c.format)
+		assert.True(t, strings.Contains(mustRender(t, renderer), c.contains), c.format)
+	}
+}
+
+func TestNewRenderer_Invalid(t *testing.T) {
+	_, err := NewRenderer(FormatMermaid, "gantt")
+	assert.EqualError(t, err, "invalid diagram type: gantt")
+
+	_, err = NewRenderer("svg", "flowchart")
+	assert.EqualError(t, err, "invalid format: svg")
+
+	_, err = NewRenderer("foo", "sequence")
+	assert.EqualError(t, err, "invalid format: foo")
+
+	_, err = NewRenderer(FormatDOT, "sequence")
+	assert.EqualError(t, err, "diagram type sequence is not supported by the dot format")
+}
+
+func mustRender(t *testing.T, renderer Renderer) string {
+	output, err := renderer.Render(sampleTree())
+	assert.NoError(t, err)
+	return output
+}
+
+func TestNewRenderer_View(t *testing.T) {
+	shared := func(id string) *github.UsesNode {
+		return &github.UsesNode{Name: id, UniqueID: "root/" + id, Kind: github.NodeKindJob, Uses: "./.github/workflows/build.yml", URL: "build.yml",
+			Children: []*github.UsesNode{{Name: "compile", UniqueID: "root/" + id + "/compile", Kind: github.NodeKindJob}}}
+	}
+	root := &github.UsesNode{Name: "root", UniqueID: "root", Children: []*github.UsesNode{shared("a"), shared("b")}}
+
+	tree, err := NewRenderer(FormatDOT, "flowchart")
+	assert.NoError(t, err)
+	output, err := tree.Render(root)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(output, `[label="compile"]`))
+
+	dag, err := NewRenderer(FormatDOT, "flowchart", WithView(graph.ViewDAG))
+	assert.NoError(t, err)
+	output, err = dag.Render(root)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(output, `[label="compile"]`))
+	assert.Equal(t, 1, strings.Count(output, `[label="./.github/workflows/build.yml"]`))
+
+	_, err = NewRenderer(FormatMermaid, "flowchart", WithView("list"))
+	assert.EqualError(t, err, "invalid view: list")
+}
+
+func TestNewRenderer_Detail(t *testing.T) {
+	job := &github.Job{RunsOn: github.RunsOn{Labels: []string{"ubuntu-latest"}}, Steps: []github.Step{
+		{Uses: "actions/checkout@v4"},
+		{Name: "Build", Run: "make", Shell: "bash"},
+	}}
+	root := &github.UsesNode{Name: "root", UniqueID: "root", Kind: github.NodeKindWorkflow, Children: []*github.UsesNode{
+		{Name: "build", UniqueID: "root/build", Kind: github.NodeKindJob, Job: job, Children: []*github.UsesNode{
+			{Name: "actions/checkout@v4", UniqueID: "root/build/#1", Kind: github.NodeKindAction, Uses: "actions/checkout@v4"},
+		}},
+	}}
+
+	render := func(opts ...Option) string {
+		renderer, err := NewRenderer(FormatDOT, "flowchart", opts...)
+		assert.NoError(t, err)
+		output, err := renderer.Render(root)
+		assert.NoError(t, err)
+		return output
+	}
+
+	jobs := render()
+	assert.Contains(t, jobs, `n1 [label="build"]`)
+	assert.Contains(t, jobs, `n1 -> n2;`)
+	assert.NotContains(t, jobs, "Build")
+
+	steps := render(WithDetail(graph.DetailSteps))
+	assert.Contains(t, steps, `n3 [label="Build"]`)
+	assert.Contains(t, steps, `n1 -> n2;`)
+	assert.Contains(t, steps, `n2 -> n3;`)
+	assert.NotContains(t, steps, `n1 -> n3;`)
+
+	full := render(WithDetail(graph.DetailFull))
+	assert.Contains(t, full, `n1 [label="build\nruns-on: ubuntu-latest"]`)
+	assert.Contains(t, full, `n3 [label="Build\nshell: bash"]`)
+
+	_, err := NewRenderer(FormatMermaid, "flowchart", WithDetail("all"))
+	assert.EqualError(t, err, "invalid detail: all")
+}
+
+func TestNewRenderer_DetailRepeatedUses(t *testing.T) {
+	wf := &github.Workflow{Jobs: map[string]github.Job{"build": {Steps: []github.Step{
+		{Uses: "actions/checkout@v4"},
+		{Run: "echo hi"},
+		{Uses: "actions/checkout@v4"},
+	}}}}
+	root := github.BuildUsesTree(context.Background(), "root", wf, nil, 2)
+
+	renderer, err := NewRenderer(FormatDOT, "flowchart", WithDetail(graph.DetailFull))
+	assert.NoError(t, err)
+	output, err := renderer.Render(root)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(output, `[label="actions/checkout@v4"]`))
+	for _, want := range []string{"n1 -> n2;", "n2 -> n3;", "n3 -> n4;"} {
+		assert.Contains(t, output, want)
+	}
+	assert.NotContains(t, output, "n3 -> n2;")
+
+	renderer, err = NewRenderer(FormatJSON, "flowchart")
+	assert.NoError(t, err)
+	output, err = renderer.Render(root)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(output, `"to": "root/build/#1"`))
+	assert.Equal(t, 1, strings.Count(output, `"to": "root/build/#3"`))
+}
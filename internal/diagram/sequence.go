@@ -3,39 +3,56 @@ package diagram
 import (
 	"github.com/TyphonHill/go-mermaid/diagrams/sequence"
 	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
 )
 
 // GenerateMermaidSequence generates a Mermaid sequence from a UsesNode tree using go-mermaid.
 func GenerateMermaidSequence(root *github.UsesNode) string {
-	diagram := sequence.NewDiagram()
-	nodeMap := make(map[string]*sequence.Actor)
-	buildSequenceActors(diagram, root, nodeMap)
-	addSequenceMessages(diagram, root, nodeMap)
-	return diagram.String()
+	return generateMermaidSequence(flattenTree(root))
 }
 
-func buildSequenceActors(diagram *sequence.Diagram, node *github.UsesNode, nodeMap map[string]*sequence.Actor) {
-	if node == nil {
-		return
-	}
-	if _, exists := nodeMap[node.UniqueID]; !exists {
-		nodeMap[node.UniqueID] = diagram.AddActor(node.UniqueID, node.Name, sequence.ActorParticipant)
-	}
-	for _, child := range node.Children {
-		buildSequenceActors(diagram, child, nodeMap)
+// generateMermaidSequence generates a Mermaid sequence from a flattened dependency graph: every node but the
+// triggers is a participant, and the contains, next, uses and cycle edges are messages, listed depth first.
+func generateMermaidSequence(g *flatGraph) string {
+	diagram := sequence.NewDiagram()
+	actors := make(map[string]*sequence.Actor, len(g.Nodes))
+	for _, n := range g.Nodes {
+		if !n.Trigger {
+			actors[n.ID] = diagram.AddActor(n.ID, n.Lines[0], sequence.ActorParticipant)
+		}
 	}
-}
 
-func addSequenceMessages(diagram *sequence.Diagram, node *github.UsesNode, nodeMap map[string]*sequence.Actor) {
-	if node == nil {
-		return
+	outgoing := make(map[string][]graphEdge)
+	for _, e := range g.Edges {
+		if e.Kind != graph.EdgeTypeNeeds && e.Kind != graph.EdgeTypeTriggers {
+			outgoing[e.From] = append(outgoing[e.From], e)
+		}
+	}
+	visited := make(map[string]bool, len(g.Nodes))
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+		for _, e := range outgoing[id] {
+			switch e.Kind {
+			case graph.EdgeTypeUses:
+				diagram.AddMessage(actors[e.From], actors[e.To], sequence.MessageSolidArrow, "uses")
+			case graph.EdgeTypeCycle:
+				diagram.AddMessage(actors[e.From], actors[e.To], sequence.MessageAsync, "cycle")
+			case graph.EdgeTypeNext:
+				diagram.AddMessage(actors[e.From], actors[e.To], sequence.MessageSolid, "then")
+			default:
+				diagram.AddMessage(actors[e.From], actors[e.To], sequence.MessageSolid, "contains")
+			}
+			visit(e.To)
+		}
 	}
-	from := nodeMap[node.UniqueID]
-	for _, child := range node.Children {
-		to := nodeMap[child.UniqueID]
-		if from != nil && to != nil {
-			diagram.AddMessage(from, to, sequence.MessageSolid, "uses")
+	for _, n := range g.Nodes {
+		if !n.Trigger {
+			visit(n.ID)
 		}
-		addSequenceMessages(diagram, child, nodeMap)
 	}
+	return diagram.String()
 }
@@ -0,0 +1,256 @@
+package diagram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/TyphonHill/go-mermaid/diagrams/flowchart"
+	"gopkg.in/yaml.v3"
+)
+
+// Style kinds of the nodes. The style of each kind is defined by the theme, and is the Mermaid class of the
+// nodes of that kind.
+const (
+	StyleRepository       = "repository"
+	StyleWorkflow         = "workflow"
+	StyleJob              = "job"
+	StyleReusableWorkflow = "reusableWorkflow" // job calling a reusable workflow
+	StyleStep             = "step"             // step running a script
+	StyleFirstPartyAction = "firstPartyAction" // action of the actions or github organizations
+	StyleThirdPartyAction = "thirdPartyAction"
+	StyleDockerAction     = "dockerAction"
+	StyleLocalAction      = "localAction"
+	StyleTrigger          = "trigger"
+	StyleUnresolved       = "unresolved" // reference that could not be resolved
+	StyleDepthLimit       = "depthLimit" // reference left unexpanded by the depth limit
+)
+
+// styleLabels are the legend labels of the style kinds, in legend order.
+var styleLabels = []struct{ kind, label string }{
+	{StyleRepository, "repository"},
+	{StyleWorkflow, "workflow"},
+	{StyleTrigger, "trigger"},
+	{StyleJob, "job"},
+	{StyleReusableWorkflow, "reusable workflow call"},
+	{StyleStep, "step"},
+	{StyleFirstPartyAction, "first-party action"},
+	{StyleThirdPartyAction, "third-party action"},
+	{StyleLocalAction, "local action"},
+	{StyleDockerAction, "docker action"},
+	{StyleUnresolved, "unresolved reference"},
+	{StyleDepthLimit, "depth limit"},
+}
+
+// Built-in themes.
+const (
+	ThemeDefault = "default"
+	ThemeDark    = "dark"
+)
+
+// Theme is the look of the Mermaid flowcharts: the Mermaid theme and options, and the style of each kind
+// of node. Theme files are YAML (or JSON) documents with the same fields.
+type Theme struct {
+	Mermaid MermaidOptions   `yaml:"mermaid"`
+	Styles  map[string]Style `yaml:"styles"` // by style kind
+}
+
+// MermaidOptions are the diagram wide options of a theme.
+type MermaidOptions struct {
+	Theme string         `yaml:"theme"` // Mermaid theme: default, neutral, dark, forest or base
+	Init  map[string]any `yaml:"init"`  // options of the %%{init}%% directive, e.g. themeVariables
+}
+
+// Style is the shape and colors of a kind of node. Empty fields are not set.
+type Style struct {
+	Shape       string `yaml:"shape"` // Mermaid shape name, e.g. rect, rounded, hex or cyl
+	Fill        string `yaml:"fill"`
+	Stroke      string `yaml:"stroke"`
+	Color       string `yaml:"color"`
+	StrokeWidth int    `yaml:"stroke-width"`
+	StrokeDash  string `yaml:"stroke-dasharray"`
+}
+
+// themes are the built-in themes, by name.
+var themes = map[string]*Theme{
+	ThemeDefault: {
+		Mermaid: MermaidOptions{Theme: "default"},
+		Styles: map[string]Style{
+			StyleRepository:       {Shape: "cyl", Fill: "#f1f5f9", Stroke: "#475569", Color: "#0f172a"},
+			StyleWorkflow:         {Shape: "doc", Fill: "#dbeafe", Stroke: "#1d4ed8", Color: "#1e3a8a"},
+			StyleJob:              {Shape: "rect", Fill: "#ffffff", Stroke: "#64748b", Color: "#0f172a"},
+			StyleReusableWorkflow: {Shape: "fr-rect", Fill: "#e0e7ff", Stroke: "#4338ca", Color: "#312e81"},
+			StyleStep:             {Shape: "rounded", Fill: "#f8fafc", Stroke: "#94a3b8", Color: "#334155"},
+			StyleFirstPartyAction: {Shape: "hex", Fill: "#dcfce7", Stroke: "#15803d", Color: "#14532d"},
+			StyleThirdPartyAction: {Shape: "hex", Fill: "#fef3c7", Stroke: "#b45309", Color: "#78350f"},
+			StyleLocalAction:      {Shape: "hex", Fill: "#ccfbf1", Stroke: "#0f766e", Color: "#134e4a"},
+			StyleDockerAction:     {Shape: "h-cyl", Fill: "#e0f2fe", Stroke: "#0369a1", Color: "#0c4a6e"},
+			StyleTrigger:          {Shape: "stadium", Fill: "#f3e8ff", Stroke: "#7e22ce", Color: "#581c87"},
+			StyleUnresolved:       {Color: "#b91c1c", Fill: "#fee2e2", Stroke: "#b91c1c", StrokeWidth: 2, StrokeDash: "5 5"},
+			StyleDepthLimit:       {Color: "#4b5563", Fill: "#f3f4f6", Stroke: "#9ca3af", StrokeWidth: 1, StrokeDash: "5 5"},
+		},
+	},
+	ThemeDark: {
+		Mermaid: MermaidOptions{Theme: "dark"},
+		Styles: map[string]Style{
+			StyleRepository:       {Shape: "cyl", Fill: "#1e293b", Stroke: "#94a3b8", Color: "#f1f5f9"},
+			StyleWorkflow:         {Shape: "doc", Fill: "#1e3a8a", Stroke: "#60a5fa", Color: "#dbeafe"},
+			StyleJob:              {Shape: "rect", Fill: "#111827", Stroke: "#9ca3af", Color: "#f9fafb"},
+			StyleReusableWorkflow: {Shape: "fr-rect", Fill: "#312e81", Stroke: "#818cf8", Color: "#e0e7ff"},
+			StyleStep:             {Shape: "rounded", Fill: "#1f2937", Stroke: "#6b7280", Color: "#e5e7eb"},
+			StyleFirstPartyAction: {Shape: "hex", Fill: "#14532d", Stroke: "#4ade80", Color: "#dcfce7"},
+			StyleThirdPartyAction: {Shape: "hex", Fill: "#78350f", Stroke: "#fbbf24", Color: "#fef3c7"},
+			StyleLocalAction:      {Shape: "hex", Fill: "#134e4a", Stroke: "#2dd4bf", Color: "#ccfbf1"},
+			StyleDockerAction:     {Shape: "h-cyl", Fill: "#0c4a6e", Stroke: "#38bdf8", Color: "#e0f2fe"},
+			StyleTrigger:          {Shape: "stadium", Fill: "#581c87", Stroke: "#c084fc", Color: "#f3e8ff"},
+			StyleUnresolved:       {Color: "#fecaca", Fill: "#7f1d1d", Stroke: "#f87171", StrokeWidth: 2, StrokeDash: "5 5"},
+			StyleDepthLimit:       {Color: "#d1d5db", Fill: "#374151", Stroke: "#9ca3af", StrokeWidth: 1, StrokeDash: "5 5"},
+		},
+	},
+}
+
+// shapeTable indexes the given Mermaid shapes by name.
+func shapeTable[S ~string](shapes ...S) map[string]S {
+	table := make(map[string]S, len(shapes))
+	for _, shape := range shapes {
+		table[string(shape)] = shape
+	}
+	return table
+}
+
+// flowchartShapes are the Mermaid shapes themes can use, by name.
+var flowchartShapes = shapeTable(
+	flowchart.NodeShapeProcess, flowchart.NodeShapeEvent, flowchart.NodeShapeTerminal, flowchart.NodeShapeSubprocess,
+	flowchart.NodeShapeDatabase, flowchart.NodeShapeStart, flowchart.NodeShapeOdd, flowchart.NodeShapeDecision,
+	flowchart.NodeShapePrepare, flowchart.NodeShapeInputOutput, flowchart.NodeShapeOutputInput,
+	flowchart.NodeShapeManualOperation, flowchart.NodeShapeManual, flowchart.NodeShapeStopDouble,
+	flowchart.NodeShapeText, flowchart.NodeShapeCard, flowchart.NodeShapeLinedProcess, flowchart.NodeShapeStartSmall,
+	flowchart.NodeShapeStopFramed, flowchart.NodeShapeForkJoin, flowchart.NodeShapeCollate, flowchart.NodeShapeComLink,
+	flowchart.NodeShapeDocument, flowchart.NodeShapeDelay, flowchart.NodeShapeStorage, flowchart.NodeShapeDiskStorage,
+	flowchart.NodeShapeDisplay, flowchart.NodeShapeDividedProcess, flowchart.NodeShapeExtract,
+	flowchart.NodeShapeInternalStorage, flowchart.NodeShapeJunction, flowchart.NodeShapeLinedDocument,
+	flowchart.NodeShapeLoopLimit, flowchart.NodeShapeManualFile, flowchart.NodeShapeManualInput,
+	flowchart.NodeShapeMultiDocument, flowchart.NodeShapeMultiProcess, flowchart.NodeShapePaperTape,
+	flowchart.NodeShapeStoredData, flowchart.NodeShapeSummary, flowchart.NodeShapeTaggedDocument,
+	flowchart.NodeShapeTaggedProcess,
+)
+
+// DefaultTheme returns a copy of the default theme.
+func DefaultTheme() *Theme {
+	return themes[ThemeDefault].clone()
+}
+
+// clone returns a deep copy of the theme, so callers cannot change the built-in themes.
+func (t *Theme) clone() *Theme {
+	c := &Theme{Mermaid: MermaidOptions{Theme: t.Mermaid.Theme}, Styles: maps.Clone(t.Styles)}
+	if t.Mermaid.Init != nil {
+		c.Mermaid.Init = cloneValue(t.Mermaid.Init).(map[string]any)
+	}
+	return c
+}
+
+// cloneValue returns a deep copy of a decoded YAML value.
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for key, value := range v {
+			c[key] = cloneValue(value)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, value := range v {
+			c[i] = cloneValue(value)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// LoadTheme returns a copy of the built-in theme with the given name (ThemeDefault when name is empty), or reads the
+// theme file at the given path. The styles and fields a theme file does not set are taken from the
+// default theme.
+func LoadTheme(name string) (*Theme, error) {
+	if name == "" {
+		return DefaultTheme(), nil
+	}
+	if theme, ok := themes[name]; ok {
+		return theme.clone(), nil
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) && !strings.ContainsAny(name, `./\`) {
+		return nil, fmt.Errorf("unknown theme %q: use %s, %s or the path of a theme file", name, ThemeDefault, ThemeDark)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme file: %w", err)
+	}
+	return ParseTheme(data)
+}
+
+// ParseTheme parses a YAML (or JSON) theme, on top of the default theme.
+func ParseTheme(data []byte) (*Theme, error) {
+	var t Theme
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse theme: %w", err)
+	}
+	if _, err := json.Marshal(t.Mermaid.Init); err != nil {
+		return nil, fmt.Errorf("invalid theme: init options: %w", err)
+	}
+
+	base := DefaultTheme()
+	theme := &Theme{Mermaid: t.Mermaid, Styles: make(map[string]Style, len(base.Styles))}
+	if theme.Mermaid.Theme == "" {
+		theme.Mermaid.Theme = base.Mermaid.Theme
+	}
+	for kind, style := range base.Styles {
+		theme.Styles[kind] = style
+	}
+	for kind, style := range t.Styles {
+		defaults, ok := base.Styles[kind]
+		if !ok {
+			return nil, fmt.Errorf("invalid theme: unknown style %q", kind)
+		}
+		if _, ok := flowchartShapes[style.Shape]; style.Shape != "" && !ok {
+			return nil, fmt.Errorf("invalid theme: unknown shape %q of style %s", style.Shape, kind)
+		}
+		theme.Styles[kind] = style.merge(defaults)
+	}
+	return theme, nil
+}
+
+// merge returns the style with the fields it does not set taken from defaults.
+func (s Style) merge(defaults Style) Style {
+	pick := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+	s.Shape = pick(s.Shape, defaults.Shape)
+	s.Fill = pick(s.Fill, defaults.Fill)
+	s.Stroke = pick(s.Stroke, defaults.Stroke)
+	s.Color = pick(s.Color, defaults.Color)
+	s.StrokeDash = pick(s.StrokeDash, defaults.StrokeDash)
+	if s.StrokeWidth == 0 {
+		s.StrokeWidth = defaults.StrokeWidth
+	}
+	return s
+}
+
+// initDirective returns the Mermaid %%{init}%% directive of the theme options, or "" when there are none.
+// Keys are sorted by encoding/json, so the directive is stable.
+func (t *Theme) initDirective() string {
+	if len(t.Mermaid.Init) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(t.Mermaid.Init)
+	if err != nil {
+		return ""
+	}
+	return "%%{init: " + string(data) + "}%%\n"
+}
@@ -0,0 +1,83 @@
+package diagram
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTheme_BuiltIn(t *testing.T) {
+	theme, err := LoadTheme("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultTheme(), theme)
+
+	theme, err = LoadTheme(ThemeDark)
+	assert.NoError(t, err)
+	assert.Equal(t, "dark", theme.Mermaid.Theme)
+
+	for _, theme := range themes {
+		for _, entry := range styleLabels {
+			assert.Contains(t, theme.Styles, entry.kind)
+		}
+	}
+
+	_, err = LoadTheme("solarized")
+	assert.EqualError(t, err, `unknown theme "solarized": use default, dark or the path of a theme file`)
+}
+
+func TestLoadTheme_Copy(t *testing.T) {
+	theme, err := LoadTheme(ThemeDark)
+	assert.NoError(t, err)
+	theme.Mermaid.Theme = "forest"
+	theme.Styles[StyleJob] = Style{Fill: "#000000"}
+	DefaultTheme().Styles[StyleJob] = Style{Fill: "#000000"}
+
+	assert.Equal(t, "dark", themes[ThemeDark].Mermaid.Theme)
+	assert.Equal(t, "#111827", themes[ThemeDark].Styles[StyleJob].Fill)
+	assert.Equal(t, "#ffffff", DefaultTheme().Styles[StyleJob].Fill)
+
+	original := &Theme{Mermaid: MermaidOptions{Init: map[string]any{"themeVariables": map[string]any{"fontSize": "12px"}}}}
+	c := original.clone()
+	c.Mermaid.Init["themeVariables"].(map[string]any)["fontSize"] = "20px"
+	assert.Equal(t, "12px", original.Mermaid.Init["themeVariables"].(map[string]any)["fontSize"])
+}
+
+func TestLoadTheme_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+mermaid:
+  theme: forest
+  init:
+    flowchart:
+      curve: basis
+styles:
+  job:
+    fill: "#000000"
+  thirdPartyAction:
+    shape: trap-t
+`), 0o600))
+
+	theme, err := LoadTheme(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "forest", theme.Mermaid.Theme)
+	assert.Equal(t, "%%{init: {\"flowchart\":{\"curve\":\"basis\"}}}%%\n", theme.initDirective())
+	assert.Equal(t, Style{Shape: "rect", Fill: "#000000", Stroke: "#64748b", Color: "#0f172a"}, theme.Styles[StyleJob])
+	assert.Equal(t, "trap-t", theme.Styles[StyleThirdPartyAction].Shape)
+	assert.Equal(t, DefaultTheme().Styles[StyleWorkflow], theme.Styles[StyleWorkflow])
+
+	_, err = LoadTheme(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read theme file")
+}
+
+func TestParseTheme_Invalid(t *testing.T) {
+	_, err := ParseTheme([]byte("styles:\n  pipeline:\n    fill: red\n"))
+	assert.EqualError(t, err, `invalid theme: unknown style "pipeline"`)
+
+	_, err = ParseTheme([]byte("styles:\n  job:\n    shape: star\n"))
+	assert.EqualError(t, err, `invalid theme: unknown shape "star" of style job`)
+
+	_, err = ParseTheme([]byte("styles: [job]"))
+	assert.ErrorContains(t, err, "failed to parse theme")
+}
@@ -0,0 +1,55 @@
+package github
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action represents a GitHub Action metadata file (action.yml or action.yaml).
+type Action struct {
+	Name        string                  `yaml:"name"`
+	Description string                  `yaml:"description"`
+	Inputs      map[string]ActionInput  `yaml:"inputs"`
+	Outputs     map[string]ActionOutput `yaml:"outputs"`
+	Runs        ActionRuns              `yaml:"runs"`
+}
+
+// ActionInput represents an input declared by an action.
+type ActionInput struct {
+	Description        string `yaml:"description"`
+	Required           bool   `yaml:"required"`
+	Default            string `yaml:"default"`
+	DeprecationMessage string `yaml:"deprecationMessage"`
+}
+
+// ActionOutput represents an output declared by an action.
+type ActionOutput struct {
+	Description string `yaml:"description"`
+	Value       string `yaml:"value"`
+}
+
+// ActionRuns describes how an action is executed.
+type ActionRuns struct {
+	Using string `yaml:"using"` // "composite", "docker", "node20", ...
+	Main  string `yaml:"main"`
+	Image string `yaml:"image"`
+	Steps []Step `yaml:"steps"`
+}
+
+// ParseActionYAML parses the action metadata YAML into an Action struct.
+func ParseActionYAML(data []byte) (*Action, error) {
+	var action Action
+	if err := yaml.Unmarshal(data, &action); err != nil {
+		return nil, fmt.Errorf("failed to parse action YAML: %w", err)
+	}
+	if action.Runs.Using == "" {
+		return nil, fmt.Errorf("invalid action: missing runs.using")
+	}
+	return &action, nil
+}
+
+// IsComposite reports whether the action is a composite action.
+func (a *Action) IsComposite() bool {
+	return a.Runs.Using == "composite"
+}
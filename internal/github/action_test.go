@@ -0,0 +1,58 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseActionYAML_Composite(t *testing.T) {
+	yamlData := []byte(`
+name: Setup
+description: Sets things up
+inputs:
+  version:
+    description: Tool version
+    required: true
+    default: "1.0"
+outputs:
+  path:
+    description: Install path
+    value: ${{ steps.install.outputs.path }}
+runs:
+  using: composite
+  steps:
+    - uses: actions/checkout@v4
+    - name: Install
+      run: ./install.sh
+      shell: bash
+`)
+	action, err := ParseActionYAML(yamlData)
+	assert.NoError(t, err)
+	assert.Equal(t, "Setup", action.Name)
+	assert.Equal(t, "Sets things up", action.Description)
+	assert.True(t, action.Inputs["version"].Required)
+	assert.Equal(t, "1.0", action.Inputs["version"].Default)
+	assert.Equal(t, "${{ steps.install.outputs.path }}", action.Outputs["path"].Value)
+	assert.True(t, action.IsComposite())
+	assert.Len(t, action.Runs.Steps, 2)
+	assert.Equal(t, "actions/checkout@v4", action.Runs.Steps[0].Uses)
+	assert.Equal(t, "Install", action.Runs.Steps[1].Name)
+}
+
+func TestParseActionYAML_Node(t *testing.T) {
+	action, err := ParseActionYAML([]byte("name: JS\nruns:\n  using: node20\n  main: dist/index.js\n"))
+	assert.NoError(t, err)
+	assert.False(t, action.IsComposite())
+	assert.Equal(t, "dist/index.js", action.Runs.Main)
+}
+
+func TestParseActionYAML_MissingRuns(t *testing.T) {
+	_, err := ParseActionYAML([]byte("name: Empty"))
+	assert.Error(t, err)
+}
+
+func TestParseActionYAML_InvalidYAML(t *testing.T) {
+	_, err := ParseActionYAML([]byte("invalid: [unclosed"))
+	assert.Error(t, err)
+}
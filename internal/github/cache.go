@@ -0,0 +1,298 @@
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached workflows and actions are considered fresh.
+const DefaultCacheTTL = 24 * time.Hour
+
+// missSuffix marks cache entries recording that a URL does not exist (HTTP 404). Private repositories
+// are not found without the right token, so these entries are also keyed by the credential of the
+// downloader (see missPath).
+const missSuffix = ".404"
+
+// credentialProvider is implemented by downloaders authenticating their requests.
+type credentialProvider interface {
+	CredentialID() string
+}
+
+// CachedDownloader is a WorkflowDownloader decorator that stores remote workflows and actions on disk.
+// Entries are keyed by owner/repo/ref/path; local files are never cached.
+// When a download fails, an expired entry is still served so that repeated runs work offline.
+type CachedDownloader struct {
+	next WorkflowDownloader
+	dir  string
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewCachedDownloader wraps next with an on-disk cache stored in dir.
+func NewCachedDownloader(next WorkflowDownloader, dir string, ttl time.Duration) *CachedDownloader {
+	return &CachedDownloader{next: next, dir: dir, ttl: ttl, now: time.Now}
+}
+
+// DefaultCacheDir returns the default cache directory, under the user cache dir.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "wk2mmd"), nil
+}
+
+// cacheEntryPattern matches the file names of cache entries (see cacheEntryName and missSuffix).
+var cacheEntryPattern = regexp.MustCompile(`^[0-9a-f]{64}((\.[0-9a-f]{16})?\.404)?$`)
+
+// ClearCache removes the entries of the cache stored in dir, and the directories left empty. Only the
+// files laid out like cache entries are removed, so that a cache directory shared with other files
+// keeps them.
+func ClearCache(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	// Entries are stored in dir, or under an owner/repo or host/owner directory.
+	empty, err := clearEntries(dir, 2)
+	if err == nil && empty {
+		err = os.Remove(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
+
+// clearEntries removes the cache entries of dir and of its subdirectories down to depth levels, and the
+// subdirectories it leaves empty. It reports whether dir was left empty by the removal.
+func clearEntries(dir string, depth int) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	empty := len(entries) > 0
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.Type().IsRegular() && cacheEntryPattern.MatchString(entry.Name()):
+			if err := os.Remove(path); err != nil {
+				return false, err
+			}
+		case entry.IsDir() && depth > 0:
+			cleared, err := clearEntries(path, depth-1)
+			if err != nil {
+				return false, err
+			}
+			if !cleared {
+				empty = false
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return false, err
+			}
+		default:
+			empty = false
+		}
+	}
+	return empty, nil
+}
+
+// DownloadWorkflow returns the cached content of url, downloading it with the wrapped downloader when
+// the entry is missing or expired.
+func (c *CachedDownloader) DownloadWorkflow(ctx context.Context, url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return c.next.DownloadWorkflow(ctx, url)
+	}
+
+	return c.cached(ctx, cacheFileName(url, HostOf(c.next)), url, func() ([]byte, error) {
+		return c.next.DownloadWorkflow(ctx, url)
+	})
+}
+
+// GetContents returns the cached content of a file read through the contents API, keyed by
+// owner/repo/ref/path like raw downloads.
+func (c *CachedDownloader) GetContents(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	fetcher, ok := c.next.(ContentsFetcher)
+	if !ok {
+		return nil, fmt.Errorf("the contents API is not supported by this client")
+	}
+	key := hostKey(HostOf(c.next), strings.Join([]string{owner, repo, ref, path}, "/"))
+	return c.cached(ctx, cacheEntryName(key), key, func() ([]byte, error) {
+		return fetcher.GetContents(ctx, owner, repo, path, ref)
+	})
+}
+
+// ResolveRef returns the cached resolution of a reference. Entries expire like any other entry, so that
+// branches are eventually resolved to their new commit.
+func (c *CachedDownloader) ResolveRef(ctx context.Context, owner, repo, ref string) (*ResolvedRef, error) {
+	fetcher, ok := c.next.(ContentsFetcher)
+	if !ok {
+		return nil, fmt.Errorf("resolving references is not supported by this client")
+	}
+	key := hostKey(HostOf(c.next), owner+"/"+repo+"/@"+ref)
+	data, err := c.cached(ctx, cacheEntryName(key), key, func() ([]byte, error) {
+		resolved, err := fetcher.ResolveRef(ctx, owner, repo, ref)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(resolved)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var resolved ResolvedRef
+	if err := json.Unmarshal(data, &resolved); err != nil {
+		return nil, fmt.Errorf("failed to parse cached reference: %w", err)
+	}
+	return &resolved, nil
+}
+
+// cached returns the content of the cache entry name, calling fetch when the entry is missing or expired.
+// Not found errors are cached too, for the credential of the downloader only; on other errors an expired entry is served when available, unless
+// the context was canceled.
+func (c *CachedDownloader) cached(ctx context.Context, name, label string, fetch func() ([]byte, error)) ([]byte, error) {
+	path := filepath.Join(c.dir, name)
+	if data, fresh, err := c.read(path); err == nil && fresh {
+		slog.Debug("Cache hit", "url", label)
+		return data, nil
+	}
+	miss := c.missPath(path)
+	if _, fresh, err := c.read(miss); err == nil && fresh {
+		slog.Debug("Cache hit (not found)", "url", label)
+		return nil, &StatusError{StatusCode: http.StatusNotFound}
+	}
+
+	data, err := fetch()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if isNotFound(err) {
+			c.write(miss, nil)
+			return nil, err
+		}
+		if stale, _, readErr := c.read(path); readErr == nil {
+			slog.Warn("Download failed, using expired cache entry", "url", label, "error", err)
+			return stale, nil
+		}
+		return nil, err
+	}
+
+	c.write(path, data)
+	return data, nil
+}
+
+// missPath returns the path of the entry recording that the entry at path does not exist, for the
+// credential of the wrapped downloader.
+func (c *CachedDownloader) missPath(path string) string {
+	if id := c.CredentialID(); id != "" {
+		return path + "." + id + missSuffix
+	}
+	return path + missSuffix
+}
+
+// GitHubHost returns the host of the wrapped downloader.
+func (c *CachedDownloader) GitHubHost() string {
+	return HostOf(c.next)
+}
+
+// CredentialID returns the credential identifier of the wrapped downloader, or "" for anonymous access.
+func (c *CachedDownloader) CredentialID() string {
+	if p, ok := c.next.(credentialProvider); ok {
+		return p.CredentialID()
+	}
+	return ""
+}
+
+// ListWorkflows delegates to the wrapped downloader when it can list remote workflows.
+func (c *CachedDownloader) ListWorkflows(ctx context.Context, owner, repo, ref string) ([]string, error) {
+	lister, ok := c.next.(WorkflowLister)
+	if !ok {
+		return nil, fmt.Errorf("listing remote workflows is not supported by this client")
+	}
+	return lister.ListWorkflows(ctx, owner, repo, ref)
+}
+
+// read returns the content of a cache entry and whether it is still fresh.
+func (c *CachedDownloader) read(path string) ([]byte, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, c.now().Sub(info.ModTime()) < c.ttl, nil
+}
+
+// write stores a cache entry. Failures are logged and otherwise ignored: the cache is best effort.
+func (c *CachedDownloader) write(path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		slog.Warn("Failed to create cache directory", "dir", filepath.Dir(path), "error", err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		slog.Warn("Failed to write cache entry", "path", path, "error", err)
+		return
+	}
+	// The entry time comes from the cache clock so that TTL checks use a single time source.
+	now := c.now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		slog.Debug("Failed to update cache entry time", "path", path, "error", err)
+	}
+}
+
+// cacheKey returns the owner/repo/ref/path key of a raw content URL of host, or the URL itself for other
+// URLs. Keys of GitHub Enterprise Server files are prefixed with the host name.
+func cacheKey(url, host string) string {
+	url = convertToRawURL(url, host)
+	if !IsEnterpriseHost(host) {
+		re := regexp.MustCompile(`^https?://raw\.githubusercontent\.com/(.+)$`)
+		if matches := re.FindStringSubmatch(url); len(matches) == 2 {
+			key, _, _ := strings.Cut(matches[1], "?")
+			return key
+		}
+		return url
+	}
+	re := regexp.MustCompile(`^` + repoURLPattern(host) + `(.+)$`)
+	if matches := re.FindStringSubmatch(url); len(matches) == 4 {
+		key, _, _ := strings.Cut(matches[3], "?")
+		return hostKey(host, matches[1]+"/"+matches[2]+"/"+key)
+	}
+	return url
+}
+
+// hostKey prefixes a cache key with the host name on GitHub Enterprise Server, so that repositories with
+// the same name on different hosts do not share entries.
+func hostKey(host, key string) string {
+	if IsEnterpriseHost(host) {
+		return NormalizeHost(host) + "/" + key
+	}
+	return key
+}
+
+// cacheFileName returns the file name of the cache entry for url, grouped by owner/repo when known.
+func cacheFileName(url, host string) string {
+	return cacheEntryName(cacheKey(url, host))
+}
+
+// cacheEntryName returns the file name of the cache entry for key, grouped by owner/repo when known.
+func cacheEntryName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	if parts := strings.SplitN(key, "/", 3); !strings.Contains(key, "://") && len(parts) == 3 && parts[0] != ".." && parts[1] != ".." {
+		return filepath.Join(parts[0], parts[1], name)
+	}
+	return name
+}
@@ -0,0 +1,238 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingDownloader struct {
+	calls int
+	data  []byte
+	err   error
+}
+
+func (d *countingDownloader) DownloadWorkflow(ctx context.Context, url string) ([]byte, error) {
+	d.calls++
+	return d.data, d.err
+}
+
+const cachedURL = "https://raw.githubusercontent.com/owner/repo/refs/heads/main/.github/workflows/ci.yml"
+
+func TestCachedDownloader_HitAndExpiry(t *testing.T) {
+	next := &countingDownloader{data: []byte("jobs: {}")}
+	cache := NewCachedDownloader(next, t.TempDir(), time.Hour)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		data, err := cache.DownloadWorkflow(context.Background(), cachedURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "jobs: {}", string(data))
+	}
+	assert.Equal(t, 1, next.calls)
+
+	now = now.Add(2 * time.Hour)
+	_, err := cache.DownloadWorkflow(context.Background(), cachedURL)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestCachedDownloader_StaleOnError(t *testing.T) {
+	next := &countingDownloader{data: []byte("jobs: {}")}
+	cache := NewCachedDownloader(next, t.TempDir(), time.Hour)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.DownloadWorkflow(context.Background(), cachedURL)
+	assert.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	next.data, next.err = nil, errors.New("network is unreachable")
+	data, err := cache.DownloadWorkflow(context.Background(), cachedURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "jobs: {}", string(data))
+}
+
+func TestCachedDownloader_NotFound(t *testing.T) {
+	next := &countingDownloader{err: &StatusError{StatusCode: http.StatusNotFound}}
+	cache := NewCachedDownloader(next, t.TempDir(), time.Hour)
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.DownloadWorkflow(context.Background(), cachedURL)
+		var statusErr *StatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	}
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestCachedDownloader_OtherErrorsNotCached(t *testing.T) {
+	next := &countingDownloader{err: &StatusError{StatusCode: http.StatusInternalServerError}}
+	cache := NewCachedDownloader(next, t.TempDir(), time.Hour)
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.DownloadWorkflow(context.Background(), cachedURL)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestCachedDownloader_LocalFilesBypassCache(t *testing.T) {
+	dir := t.TempDir()
+	next := &countingDownloader{data: []byte("jobs: {}")}
+	cache := NewCachedDownloader(next, dir, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.DownloadWorkflow(context.Background(), ".github/workflows/ci.yml")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, next.calls)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCachedDownloader_ListWorkflows(t *testing.T) {
+	cache := NewCachedDownloader(&countingDownloader{}, t.TempDir(), time.Hour)
+	_, err := cache.ListWorkflows(context.Background(), "owner", "repo", "")
+	assert.Error(t, err)
+}
+
+// countingContentsClient is a ContentsFetcher counting API calls.
+type countingContentsClient struct {
+	countingDownloader
+	resolves, reads int
+}
+
+func (c *countingContentsClient) ResolveRef(ctx context.Context, owner, repo, ref string) (*ResolvedRef, error) {
+	c.resolves++
+	return &ResolvedRef{SHA: "abc", Kind: RefKindBranch}, nil
+}
+
+func (c *countingContentsClient) GetContents(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	c.reads++
+	return []byte("jobs: {}"), nil
+}
+
+func TestCachedDownloader_ContentsAPI(t *testing.T) {
+	next := &countingContentsClient{}
+	cache := NewCachedDownloader(next, t.TempDir(), time.Hour)
+
+	for range 2 {
+		resolved, err := cache.ResolveRef(context.Background(), "owner", "repo", "main")
+		assert.NoError(t, err)
+		assert.Equal(t, &ResolvedRef{SHA: "abc", Kind: RefKindBranch}, resolved)
+
+		data, err := cache.GetContents(context.Background(), "owner", "repo", "ci.yml", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "jobs: {}", string(data))
+	}
+	assert.Equal(t, 1, next.resolves)
+	assert.Equal(t, 1, next.reads)
+
+	// Contents are keyed like raw downloads of the same file.
+	_, err := cache.DownloadWorkflow(context.Background(), "https://raw.githubusercontent.com/owner/repo/abc/ci.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, next.calls)
+
+	unsupported := NewCachedDownloader(&countingDownloader{}, t.TempDir(), time.Hour)
+	_, err = unsupported.ResolveRef(context.Background(), "owner", "repo", "main")
+	assert.Error(t, err)
+	_, err = unsupported.GetContents(context.Background(), "owner", "repo", "ci.yml", "abc")
+	assert.Error(t, err)
+}
+
+func TestCacheKey(t *testing.T) {
+	assert.Equal(t, "owner/repo/main/ci.yml", cacheKey("https://raw.githubusercontent.com/owner/repo/main/ci.yml?token=abc", DefaultHost))
+	assert.Equal(t, "owner/repo/main/ci.yml", cacheKey("https://github.com/owner/repo/blob/main/ci.yml", DefaultHost))
+	assert.Equal(t, "https://example.com/ci.yml", cacheKey("https://example.com/ci.yml", DefaultHost))
+
+	const ghes = "ghes.example.com"
+	assert.Equal(t, "ghes.example.com/owner/repo/main/ci.yml", cacheKey("https://ghes.example.com/owner/repo/raw/main/ci.yml?token=abc", ghes))
+	assert.Equal(t, "ghes.example.com/owner/repo/main/ci.yml", cacheKey("https://ghes.example.com/owner/repo/blob/main/ci.yml", ghes))
+	assert.Equal(t, "ghes.example.com/owner/repo/main/ci.yml", cacheKey("https://ghes.example.com/raw/owner/repo/main/ci.yml", ghes))
+
+	assert.Equal(t, "owner", filepath.Dir(filepath.Dir(cacheFileName(cachedURL, DefaultHost))))
+	assert.Equal(t, cacheFileName(cachedURL, DefaultHost), cacheFileName(cachedURL+"?token=abc", DefaultHost))
+	assert.NotEqual(t, cacheFileName(cachedURL, DefaultHost), cacheFileName(cachedURL+"/action.yml", DefaultHost))
+}
+
+func TestClearCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache := NewCachedDownloader(&countingDownloader{data: []byte("x")}, dir, time.Hour)
+	_, err := cache.DownloadWorkflow(context.Background(), cachedURL)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ClearCache(dir))
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestClearCache_KeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCachedDownloader(&countingDownloader{data: []byte("x")}, dir, time.Hour)
+	_, err := cache.DownloadWorkflow(context.Background(), cachedURL)
+	assert.NoError(t, err)
+	_, err = cache.DownloadWorkflow(context.Background(), "https://example.com/ci.yml")
+	assert.NoError(t, err)
+
+	foreign := []string{filepath.Join(dir, "notes.txt"), filepath.Join(dir, "owner", "repo", "notes.txt"), filepath.Join(dir, "project", "main.go")}
+	for _, path := range foreign {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		assert.NoError(t, os.WriteFile(path, []byte("keep"), 0o600))
+	}
+
+	assert.NoError(t, ClearCache(dir))
+	for _, path := range foreign {
+		_, err := os.Stat(path)
+		assert.NoError(t, err)
+	}
+	for _, entry := range []string{cacheFileName(cachedURL, DefaultHost), cacheFileName("https://example.com/ci.yml", DefaultHost)} {
+		_, err := os.Stat(filepath.Join(dir, entry))
+		assert.True(t, os.IsNotExist(err))
+	}
+	assert.NoError(t, ClearCache(filepath.Join(dir, "missing")))
+}
+
+type authenticatedDownloader struct {
+	countingDownloader
+	credential string
+}
+
+func (d *authenticatedDownloader) CredentialID() string {
+	return d.credential
+}
+
+func TestCachedDownloader_NotFoundPerCredential(t *testing.T) {
+	dir := t.TempDir()
+	notFound := &StatusError{StatusCode: http.StatusNotFound}
+	actionURL := cachedURL + "/action.yml"
+	_, err := NewCachedDownloader(&countingDownloader{err: notFound}, dir, time.Hour).DownloadWorkflow(context.Background(), cachedURL)
+	assert.Error(t, err)
+	first := &authenticatedDownloader{countingDownloader: countingDownloader{err: notFound}, credential: NewClient("first").CredentialID()}
+	for i := 0; i < 2; i++ {
+		_, err = NewCachedDownloader(first, dir, time.Hour).DownloadWorkflow(context.Background(), actionURL)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 1, first.calls)
+
+	// A private repository is not found anonymously, but is with a token.
+	second := &authenticatedDownloader{countingDownloader: countingDownloader{data: []byte("jobs: {}")}, credential: NewClient("second").CredentialID()}
+	for _, url := range []string{cachedURL, actionURL} {
+		data, err := NewCachedDownloader(second, dir, time.Hour).DownloadWorkflow(context.Background(), url)
+		assert.NoError(t, err)
+		assert.Equal(t, "jobs: {}", string(data))
+	}
+	assert.Equal(t, 2, second.calls)
+
+	assert.NoError(t, ClearCache(dir))
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
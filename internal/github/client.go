@@ -1,35 +1,96 @@
 package github
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 )
 
 const baseURL = "https://api.github.com"
 
+// StatusError is returned when a request completes with an unexpected HTTP status code.
+type StatusError struct {
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
 // Client is a GitHub API client for downloading workflows and actions.
+// Failed requests are retried with exponential backoff, and rate limited requests are retried
+// once the limit resets (see Do).
 type Client struct {
 	Token      string
+	Host       string // github.com or the host name of a GitHub Enterprise Server
 	httpClient *http.Client
+	maxRetries int
+	sleep      func(context.Context, time.Duration) error
+	now        func() time.Time
 }
 
-// NewClient creates a new GitHub API client with the given token.
+// NewClient creates a new github.com API client with the given token.
 func NewClient(token string) *Client {
+	return NewClientForHost(token, DefaultHost)
+}
+
+// NewClientForHost creates a new API client with the given token for github.com or a GitHub Enterprise
+// Server host (whose API is served under /api/v3).
+func NewClientForHost(token, host string) *Client {
 	return &Client{
 		Token:      token,
-		httpClient: http.DefaultClient,
+		Host:       NormalizeHost(host),
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		sleep:      sleep,
+		now:        time.Now,
+	}
+}
+
+// SetTimeout sets the timeout of each HTTP request (0 means no timeout) and returns the client for chaining.
+func (c *Client) SetTimeout(timeout time.Duration) *Client {
+	c.httpClient.Timeout = timeout
+	return c
+}
+
+// SetMaxRetries sets how many times a failed request is retried and returns the client for chaining.
+func (c *Client) SetMaxRetries(retries int) *Client {
+	c.maxRetries = max(retries, 0)
+	return c
+}
+
+// GitHubHost returns the host the client is bound to.
+func (c *Client) GitHubHost() string {
+	return c.Host
+}
+
+// CredentialID returns a short digest identifying the token of the client, or "" for anonymous access.
+// The token cannot be recovered from it.
+func (c *Client) CredentialID() string {
+	if c.Token == "" {
+		return ""
 	}
+	sum := sha256.Sum256([]byte(c.Token))
+	return hex.EncodeToString(sum[:8])
 }
 
-// newRequest creates a new HTTP request with the given method and path, using the baseURL unless path is a full URL.
+// newRequest creates a new HTTP request with the given method and path, using the API root of the client
+// host (baseURL for github.com) unless path is a full URL.
 // It adds the Authorization header if a token is set.
-func (c *Client) newRequest(method, path string) (*http.Request, error) {
+func (c *Client) newRequest(ctx context.Context, method, path string) (*http.Request, error) {
 	url := path
 	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
-		url = fmt.Sprintf("%s%s", baseURL, path)
+		url = fmt.Sprintf("%s%s", apiBaseURL(c.Host), path)
 	}
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -39,7 +100,77 @@ func (c *Client) newRequest(method, path string) (*http.Request, error) {
 	return req, nil
 }
 
-// Do executes the HTTP request using the client's httpClient.
+// Do executes the HTTP request using the client's httpClient. Network errors and 5xx responses are
+// retried with exponential backoff; rate limited responses (403/429) are retried after the delay given
+// by Retry-After or X-RateLimit-Reset, unless it is too long, in which case a *RateLimitError is returned.
+// Requests with a body are not retried.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	return c.httpClient.Do(req)
+	for attempt := 0; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		retry := attempt < c.maxRetries && req.Body == nil
+		if err != nil {
+			if !retry || req.Context().Err() != nil {
+				return nil, err
+			}
+			wait := backoff(attempt)
+			slog.Debug("Request failed, retrying", "url", req.URL.String(), "error", err, "wait", wait)
+			if err := c.sleep(req.Context(), wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if rateLimitErr := c.rateLimitError(resp); rateLimitErr != nil {
+			closeBody(resp)
+			wait := rateLimitErr.Reset.Sub(c.now())
+			if !retry || wait > maxRateLimitWait {
+				return nil, rateLimitErr
+			}
+			slog.Warn("GitHub API rate limit reached, waiting for reset", "url", req.URL.String(), "wait", wait.Round(time.Second))
+			if err := c.sleep(req.Context(), max(wait, 0)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode >= http.StatusInternalServerError && retry {
+			closeBody(resp)
+			wait := backoff(attempt)
+			slog.Debug("Server error, retrying", "url", req.URL.String(), "status", resp.StatusCode, "wait", wait)
+			if err := c.sleep(req.Context(), wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return resp, nil
+	}
+}
+
+// get performs a GET request on path with the given Accept header and returns the response body.
+// A response with a status other than 200 is returned as a *StatusError.
+func (c *Client) get(ctx context.Context, path, accept string) ([]byte, error) {
+	req, err := c.newRequest(ctx, "GET", path)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error closing response body: %v\n", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return data, nil
 }
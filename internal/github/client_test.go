@@ -1,6 +1,7 @@
 package github
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -17,7 +18,7 @@ func TestNewRequest(t *testing.T) {
 	client := NewClient("token123")
 	method := "GET"
 	path := "/test/path"
-	req, err := client.newRequest(method, path)
+	req, err := client.newRequest(context.Background(), method, path)
 	assert.NoError(t, err)
 	assert.Equal(t, "GET", req.Method)
 	assert.Equal(t, "https://api.github.com/test/path", req.URL.String())
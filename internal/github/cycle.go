@@ -0,0 +1,88 @@
+package github
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// expansion is a workflow or action being expanded, linked to the document it is referenced from.
+type expansion struct {
+	key    string // identity of the document, see documentKey
+	nodeID string // UniqueID of the node the document is expanded under
+	uses   string // 'uses' reference of the document, or the URL of the root workflow
+	parent *expansion
+}
+
+// push returns the expansion of wf under the node nodeID, referenced as uses from the expansion e.
+func (e *expansion) push(wf *Workflow, nodeID, uses string) *expansion {
+	return &expansion{key: documentKey(wf), nodeID: nodeID, uses: uses, parent: e}
+}
+
+// find returns the expansion of the document of wf in the chain, or nil if it is not being expanded.
+// Documents without a URL (built in code) are never found.
+func (e *expansion) find(wf *Workflow) *expansion {
+	key := documentKey(wf)
+	if key == "" {
+		return nil
+	}
+	for ; e != nil; e = e.parent {
+		if e.key == key {
+			return e
+		}
+	}
+	return nil
+}
+
+// cycle returns the references forming the cycle closed by referencing again, as uses, the document of
+// the ancestor expansion: from the ancestor to the last expansion of the chain, followed by uses.
+func (e *expansion) cycle(ancestor *expansion, uses string) []string {
+	var refs []string
+	for ; e != ancestor.parent; e = e.parent {
+		refs = append(refs, e.uses)
+	}
+	for i, j := 0, len(refs)-1; i < j; i, j = i+1, j-1 {
+		refs[i], refs[j] = refs[j], refs[i]
+	}
+	return append(refs, uses)
+}
+
+// documentKey returns the identity of a fetched workflow or action: its URL, or its absolute path for
+// local files, so that the same file reached through different relative paths is recognized.
+func documentKey(wf *Workflow) string {
+	if wf.URL == "" || strings.Contains(wf.URL, "://") {
+		return wf.URL
+	}
+	if abs, err := filepath.Abs(wf.URL); err == nil {
+		return abs
+	}
+	return wf.URL
+}
+
+// Cycle is a chain of references leading back to a workflow or action that is already being expanded.
+type Cycle struct {
+	NodeID string   // UniqueID of the node closing the cycle
+	Uses   []string // references of the cycle, starting and ending with the same document
+}
+
+// String returns the references of the cycle joined by arrows.
+func (c Cycle) String() string {
+	return strings.Join(c.Uses, " -> ")
+}
+
+// Cycles returns the reference cycles of the tree, in depth first order.
+func Cycles(root *UsesNode) []Cycle {
+	var cycles []Cycle
+	var visit func(n *UsesNode)
+	visit = func(n *UsesNode) {
+		if len(n.Cycle) > 0 {
+			cycles = append(cycles, Cycle{NodeID: n.UniqueID, Uses: n.Cycle})
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	if root != nil {
+		visit(root)
+	}
+	return cycles
+}
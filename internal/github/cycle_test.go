@@ -0,0 +1,68 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cyclicFiles are workflows and actions referencing each other in cycles.
+var cyclicFiles = map[string]string{
+	".github/workflows/ci.yml":     "jobs:\n  a:\n    uses: ./.github/workflows/a.yml\n  self:\n    uses: ./.github/workflows/self.yml\n  build:\n    steps:\n      - uses: ./actions/loop\n",
+	"./.github/workflows/a.yml":    "jobs:\n  call-b:\n    uses: ./.github/workflows/b.yml\n",
+	"./.github/workflows/b.yml":    "jobs:\n  call-a:\n    uses: ./.github/workflows/a.yml\n",
+	"./.github/workflows/self.yml": "jobs:\n  call-ci:\n    uses: ./.github/workflows/ci.yml\n",
+	"./.github/workflows/ci.yml":   "jobs:\n  build:\n    runs-on: ubuntu-latest\n",
+	"./actions/loop/action.yml":    "runs:\n  using: composite\n  steps:\n    - uses: ./actions/loop\n",
+}
+
+func cyclicFetcher(client WorkflowDownloader) func(context.Context, string) (*Workflow, error) {
+	resolver := NewResolver(client, 2)
+	return func(ctx context.Context, uses string) (*Workflow, error) {
+		ar, err := ParseActionRef(uses, Repository{})
+		if err != nil {
+			return nil, err
+		}
+		return resolver.Resolve(ctx, ar)
+	}
+}
+
+func TestBuildUsesTree_DetectsCycles(t *testing.T) {
+	client := &slowDownloader{files: cyclicFiles}
+	wf, err := ParseWorkflowYAML(".github/workflows/ci.yml", []byte(cyclicFiles[".github/workflows/ci.yml"]))
+	assert.NoError(t, err)
+
+	tree := BuildUsesTree(context.Background(), "workflow", wf, cyclicFetcher(client), 20)
+	assert.Equal(t, []Cycle{
+		{NodeID: "workflow/a/call-b/call-a", Uses: []string{"./.github/workflows/a.yml", "./.github/workflows/b.yml", "./.github/workflows/a.yml"}},
+		{NodeID: "workflow/self/call-ci", Uses: []string{".github/workflows/ci.yml", "./.github/workflows/self.yml", "./.github/workflows/ci.yml"}},
+		{NodeID: "workflow/build/#1/#1", Uses: []string{"./actions/loop", "./actions/loop"}},
+	}, Cycles(tree))
+
+	callA := tree.Children[0].Children[0].Children[0]
+	assert.Equal(t, "workflow/a", callA.CycleTo)
+	assert.Empty(t, callA.Children)
+	assert.Equal(t, "workflow", tree.Children[1].Children[0].CycleTo)
+	assert.Equal(t, "./.github/workflows/a.yml -> ./.github/workflows/b.yml -> ./.github/workflows/a.yml", Cycles(tree)[0].String())
+}
+
+func TestPrefetchAndCollectAllUses_StopAtCycles(t *testing.T) {
+	client := &slowDownloader{files: cyclicFiles}
+	wf, err := ParseWorkflowYAML(".github/workflows/ci.yml", []byte(cyclicFiles[".github/workflows/ci.yml"]))
+	assert.NoError(t, err)
+	fetcher := cyclicFetcher(client)
+
+	Prefetch(context.Background(), wf, fetcher, 1000, DefaultConcurrency)
+	for url, calls := range client.calls {
+		assert.Equal(t, 1, calls, url)
+	}
+
+	uses := CollectAllUses(context.Background(), wf, fetcher, 1000)
+	assert.Equal(t, []string{"./.github/workflows/a.yml", "./.github/workflows/b.yml", "./.github/workflows/a.yml",
+		"./.github/workflows/self.yml", "./.github/workflows/ci.yml"}, uses)
+}
+
+func TestCycles_Nil(t *testing.T) {
+	assert.Empty(t, Cycles(nil))
+}
@@ -0,0 +1,96 @@
+package github
+
+import "context"
+
+// documentGraph is the graph of the workflows and actions reached from a workflow: one document per
+// workflow or action, whatever the number of its callers, linked to the documents its references resolve
+// to. Each reference of a document is fetched once, and the references of a document are only walked again
+// when it is reached with a larger depth, so that building the graph is linear in its number of documents
+// even when the tree view of the same references is exponential.
+type documentGraph struct {
+	ctx     context.Context
+	fetcher func(context.Context, string) (*Workflow, error)
+	docs    map[string]*document // by documentKey
+}
+
+// document is a workflow or action of a documentGraph.
+type document struct {
+	wf    *Workflow
+	depth int                   // depth the references of the document were resolved for
+	refs  map[string]*reference // by 'uses' reference, as written in the document
+}
+
+// reference is the resolution of a 'uses' reference of a document.
+type reference struct {
+	wf  *Workflow // fetched workflow or action, nil if there is nothing to expand
+	err error
+	doc *document // document of wf
+}
+
+// newDocumentGraph returns the graph of the documents reached from wf, fetching references with
+// fetcher while depth allows it. A nil fetcher resolves nothing.
+func newDocumentGraph(ctx context.Context, wf *Workflow, fetcher func(context.Context, string) (*Workflow, error), depth int) (*documentGraph, *document) {
+	g := &documentGraph{ctx: ctx, fetcher: fetcher, docs: map[string]*document{}}
+	return g, g.add(wf, depth)
+}
+
+// add returns the document of wf, resolving its references for depth: each reference is one level deeper
+// than wf and is only resolved while depth > 1. Documents without a URL (built in code) are not shared.
+func (g *documentGraph) add(wf *Workflow, depth int) *document {
+	key := documentKey(wf)
+	doc := g.docs[key]
+	if doc == nil {
+		doc = &document{wf: wf, refs: map[string]*reference{}}
+		if key != "" {
+			g.docs[key] = doc
+		}
+	}
+	// A document being resolved (a cycle) or already resolved for this depth is not walked again.
+	if depth <= doc.depth {
+		return doc
+	}
+	doc.depth = depth
+	if g.fetcher == nil || depth <= 1 {
+		return doc
+	}
+	fetcher := fetcherOf(wf, g.fetcher)
+	for _, uses := range documentUses(wf) {
+		ref := doc.refs[uses]
+		if ref == nil {
+			ref = &reference{}
+			ref.wf, ref.err = fetcher(g.ctx, uses)
+			doc.refs[uses] = ref
+		}
+		if ref.wf != nil {
+			ref.doc = g.add(ref.wf, depth-1)
+		}
+	}
+	return doc
+}
+
+// documentUses returns the 'uses' references of the jobs and steps of wf, in document order.
+func documentUses(wf *Workflow) []string {
+	var uses []string
+	addSteps := func(steps []Step) {
+		for _, step := range steps {
+			if step.Uses != "" {
+				uses = append(uses, step.Uses)
+			}
+		}
+	}
+	if wf.Action != nil {
+		if wf.Action.IsComposite() {
+			addSteps(wf.Action.Runs.Steps)
+		}
+		return uses
+	}
+	for _, jobName := range wf.JobNames() {
+		job := wf.Jobs[jobName]
+		if job.Uses != "" {
+			uses = append(uses, job.Uses)
+			continue
+		}
+		addSteps(job.Steps)
+	}
+	return uses
+}
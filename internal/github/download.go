@@ -1,6 +1,7 @@
 package github
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
@@ -11,19 +12,17 @@ import (
 
 // WorkflowDownloader defines the interface for downloading workflow files.
 type WorkflowDownloader interface {
-	DownloadWorkflow(url string) ([]byte, error)
+	DownloadWorkflow(ctx context.Context, url string) ([]byte, error)
 }
 
 // DownloadWorkflow downloads a GitHub Actions workflow YAML file from the given full URL or local file path.
-func (c *Client) DownloadWorkflow(url string) ([]byte, error) {
+func (c *Client) DownloadWorkflow(ctx context.Context, url string) ([]byte, error) {
 	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
 		slog.Debug("Downloading workflow from URL", "url", url)
 
-		if strings.Contains(url, "github.com/") && strings.Contains(url, "/blob/") {
-			url = convertToRawURL(url)
-		}
+		url = convertToRawURL(url, c.Host)
 		// Default: HTTP(S) download
-		req, err := c.newRequest("GET", url)
+		req, err := c.newRequest(ctx, "GET", url)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
@@ -38,7 +37,7 @@ func (c *Client) DownloadWorkflow(url string) ([]byte, error) {
 			}
 		}()
 		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			return nil, &StatusError{StatusCode: resp.StatusCode}
 		}
 		data, err := io.ReadAll(resp.Body)
 		if err != nil {
@@ -63,8 +62,16 @@ func (c *Client) DownloadWorkflow(url string) ([]byte, error) {
 }
 
 // convertToRawURL converts a URL of the form github.com/{owner}/{repo}/blob/{branch}/{path}
-// to raw.githubusercontent.com/{owner}/{repo}/{branch}/{path}.
-func convertToRawURL(url string) string {
+// to raw.githubusercontent.com/{owner}/{repo}/{branch}/{path}. On GitHub Enterprise Server,
+// {host}/{owner}/{repo}/blob/{branch}/{path} is converted to {host}/{owner}/{repo}/raw/{branch}/{path}.
+func convertToRawURL(url, host string) string {
+	if !strings.Contains(url, "/blob/") {
+		return url
+	}
+	if IsEnterpriseHost(host) && strings.HasPrefix(url, "https://"+NormalizeHost(host)+"/") {
+		slog.Debug("Converting GitHub Enterprise URL to raw URL", "url", url)
+		return strings.Replace(url, "/blob/", "/raw/", 1)
+	}
 	if strings.HasPrefix(url, "https://github.com/") {
 
 		slog.Debug("Converting GitHub URL to raw URL", "url", url)
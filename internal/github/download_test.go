@@ -1,6 +1,7 @@
 package github
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -26,7 +27,7 @@ func TestDownloadWorkflow_LocalFile(t *testing.T) {
 	}
 
 	client := NewClient("")
-	data, err := client.DownloadWorkflow(tmpfile.Name())
+	data, err := client.DownloadWorkflow(context.Background(), tmpfile.Name())
 	if err != nil {
 		t.Errorf("Expected no error for local file, got: %v", err)
 	}
@@ -54,7 +55,7 @@ func TestDownloadWorkflow_FileURL(t *testing.T) {
 	}
 
 	client := NewClient("")
-	data, err := client.DownloadWorkflow("file://" + tmpfile.Name())
+	data, err := client.DownloadWorkflow(context.Background(), "file://"+tmpfile.Name())
 	if err != nil {
 		t.Errorf("Expected no error for file://, got: %v", err)
 	}
@@ -65,7 +66,7 @@ func TestDownloadWorkflow_FileURL(t *testing.T) {
 
 func TestDownloadWorkflow_InvalidPath(t *testing.T) {
 	client := NewClient("")
-	_, err := client.DownloadWorkflow("/nonexistent/file/path.yml")
+	_, err := client.DownloadWorkflow(context.Background(), "/nonexistent/file/path.yml")
 	if err == nil {
 		t.Errorf("Expected error for invalid file path")
 	}
@@ -81,7 +82,7 @@ func TestDownloadWorkflow_HTTP(t *testing.T) {
 	defer ts.Close()
 	client := NewClient("")
 	client.httpClient = ts.Client()
-	data, err := client.DownloadWorkflow(ts.URL)
+	data, err := client.DownloadWorkflow(context.Background(), ts.URL)
 	if err != nil {
 		t.Errorf("Expected no error for HTTP download, got: %v", err)
 	}
@@ -100,7 +101,7 @@ func TestDownloadWorkflow_HTTPErrorStatus(t *testing.T) {
 	defer ts.Close()
 	client := NewClient("")
 	client.httpClient = ts.Client()
-	_, err := client.DownloadWorkflow(ts.URL)
+	_, err := client.DownloadWorkflow(context.Background(), ts.URL)
 	if err == nil {
 		t.Errorf("Expected error for HTTP 404")
 	}
@@ -125,9 +126,36 @@ func TestConvertToRawURL(t *testing.T) {
 		},
 	}
 	for _, c := range cases {
-		got := convertToRawURL(c.input)
+		got := convertToRawURL(c.input, DefaultHost)
 		if got != c.want {
 			t.Errorf("convertToRawURL(%q) = %q; want %q", c.input, got, c.want)
 		}
 	}
 }
+
+func TestConvertToRawURL_Enterprise(t *testing.T) {
+	const host = "ghes.example.com"
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "https://ghes.example.com/owner/repo/blob/main/.github/workflows/ci.yml",
+			want:  "https://ghes.example.com/owner/repo/raw/main/.github/workflows/ci.yml",
+		},
+		{
+			input: "https://ghes.example.com/owner/repo/raw/main/.github/workflows/ci.yml",
+			want:  "https://ghes.example.com/owner/repo/raw/main/.github/workflows/ci.yml",
+		},
+		{
+			input: "https://github.com/owner/repo/blob/main/ci.yml",
+			want:  "https://raw.githubusercontent.com/owner/repo/main/ci.yml",
+		},
+	}
+	for _, c := range cases {
+		got := convertToRawURL(c.input, host)
+		if got != c.want {
+			t.Errorf("convertToRawURL(%q, %q) = %q; want %q", c.input, host, got, c.want)
+		}
+	}
+}
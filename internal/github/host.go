@@ -0,0 +1,68 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultHost is the host name of github.com.
+const DefaultHost = "github.com"
+
+// hostProvider is implemented by downloaders bound to a GitHub host.
+type hostProvider interface {
+	GitHubHost() string
+}
+
+// NormalizeHost returns the bare host name of a GitHub instance (e.g. "github.example.com"),
+// accepting URLs such as "https://github.example.com/". An empty host means github.com.
+func NormalizeHost(host string) string {
+	host = strings.TrimSpace(strings.ToLower(host))
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host = strings.TrimSuffix(host, "/")
+	if host == "" || host == "api.github.com" {
+		return DefaultHost
+	}
+	return host
+}
+
+// IsEnterpriseHost reports whether host is a GitHub Enterprise Server rather than github.com.
+func IsEnterpriseHost(host string) bool {
+	return NormalizeHost(host) != DefaultHost
+}
+
+// HostOf returns the GitHub host the downloader is bound to, github.com by default.
+func HostOf(client WorkflowDownloader) string {
+	if p, ok := client.(hostProvider); ok {
+		return NormalizeHost(p.GitHubHost())
+	}
+	return DefaultHost
+}
+
+// apiBaseURL returns the REST API root of host: api.github.com, or /api/v3 on GitHub Enterprise Server.
+func apiBaseURL(host string) string {
+	if !IsEnterpriseHost(host) {
+		return baseURL
+	}
+	return fmt.Sprintf("https://%s/api/v3", NormalizeHost(host))
+}
+
+// rawContentURL returns the URL serving the raw content of path in owner/repo at ref.
+// github.com serves it from raw.githubusercontent.com, GitHub Enterprise Server from /raw/ paths.
+func rawContentURL(host, owner, repo, ref, path string) string {
+	if !IsEnterpriseHost(host) {
+		return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", owner, repo, ref, path)
+	}
+	return fmt.Sprintf("https://%s/%s/%s/raw/%s/%s", NormalizeHost(host), owner, repo, ref, path)
+}
+
+// repoURLPattern returns the pattern matching the scheme and host of the file URLs of host, followed by
+// owner/repo/ and an optional blob/ or raw/ segment. Enterprise hosts serve raw files from
+// host/owner/repo/raw/..., host/raw/owner/repo/... or raw.host/owner/repo/...
+func repoURLPattern(host string) string {
+	if !IsEnterpriseHost(host) {
+		return `https://(?:raw\.githubusercontent\.com|github\.com)/([^/]+)/([^/]+)/(?:blob/)?`
+	}
+	h := regexp.QuoteMeta(NormalizeHost(host))
+	return `https://(?:raw\.)?` + h + `/(?:raw/)?([^/]+)/([^/]+)/(?:blob/|raw/)?`
+}
@@ -0,0 +1,49 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	assert.Equal(t, DefaultHost, NormalizeHost(""))
+	assert.Equal(t, DefaultHost, NormalizeHost("https://api.github.com/"))
+	assert.Equal(t, "ghes.example.com", NormalizeHost("https://GHES.example.com/"))
+	assert.False(t, IsEnterpriseHost("github.com"))
+	assert.True(t, IsEnterpriseHost("ghes.example.com"))
+}
+
+func TestHostOf(t *testing.T) {
+	assert.Equal(t, DefaultHost, HostOf(&mockClient{}))
+	assert.Equal(t, DefaultHost, HostOf(NewClient("")))
+	client := NewClientForHost("", "ghes.example.com")
+	assert.Equal(t, "ghes.example.com", HostOf(client))
+	assert.Equal(t, "ghes.example.com", HostOf(NewCachedDownloader(client, t.TempDir(), 0)))
+}
+
+func TestRawContentURL(t *testing.T) {
+	assert.Equal(t, "https://raw.githubusercontent.com/owner/repo/main/a.yml", rawContentURL(DefaultHost, "owner", "repo", "main", "a.yml"))
+	assert.Equal(t, "https://ghes.example.com/owner/repo/raw/main/a.yml", rawContentURL("ghes.example.com", "owner", "repo", "main", "a.yml"))
+}
+
+func TestEnterpriseClient(t *testing.T) {
+	client := NewClientForHost("token", "ghes.example.com")
+	req, err := client.newRequest(context.Background(), "GET", "/repos/owner/repo")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://ghes.example.com/api/v3/repos/owner/repo", req.URL.String())
+
+	var requested []string
+	client.httpClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Header: make(http.Header)}, nil
+	})}
+	ar, _ := ParseActionRef("owner/repo/setup@v1", Repository{})
+	wf, err := FetchActionWorkflow(context.Background(), client, ar)
+	assert.Nil(t, wf)
+	assert.Error(t, err)
+	assert.Contains(t, requested, "https://ghes.example.com/api/v3/repos/owner/repo/git/ref/heads/v1")
+	assert.NotContains(t, requested, "https://raw.githubusercontent.com/owner/repo/refs/tags/v1/setup/action.yml")
+}
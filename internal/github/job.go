@@ -0,0 +1,231 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunsOn handles the string, []string and {group, labels} forms of the 'runs-on' field.
+type RunsOn struct {
+	Group  string
+	Labels []string
+}
+
+// Strategy represents the 'strategy' field of a job.
+// FailFast and MaxParallel are kept as strings because they may hold expressions.
+type Strategy struct {
+	Matrix      *Matrix `yaml:"matrix"`
+	FailFast    string  `yaml:"fail-fast"`
+	MaxParallel string  `yaml:"max-parallel"`
+}
+
+// Matrix represents 'strategy.matrix'. It is either an expression (e.g. ${{ fromJSON(...) }})
+// or a set of dimensions with optional include/exclude entries.
+type Matrix struct {
+	Expression     string
+	Dimensions     map[string][]any
+	DimensionOrder []string // dimension names in YAML document order
+	Include        []map[string]any
+	Exclude        []map[string]any
+}
+
+// Environment handles the string and {name, url} forms of the 'environment' field.
+type Environment struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// Concurrency handles the string and {group, cancel-in-progress} forms of the 'concurrency' field.
+type Concurrency struct {
+	Group            string `yaml:"group"`
+	CancelInProgress string `yaml:"cancel-in-progress"`
+}
+
+// Permissions handles the "read-all"/"write-all" and per-scope map forms of the 'permissions' field.
+type Permissions struct {
+	All    string
+	Scopes map[string]string
+}
+
+// Secrets handles the "inherit" and map forms of the 'secrets' field of a reusable workflow call.
+type Secrets struct {
+	Inherit bool
+	Values  map[string]string
+}
+
+// UnmarshalYAML custom unmarshal for RunsOn to support string, []string or {group, labels}.
+func (r *RunsOn) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		r.Labels = []string{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		return value.Decode(&r.Labels)
+	case yaml.MappingNode:
+		var m struct {
+			Group  string    `yaml:"group"`
+			Labels yaml.Node `yaml:"labels"`
+		}
+		if err := value.Decode(&m); err != nil {
+			return err
+		}
+		r.Group = m.Group
+		if m.Labels.Kind == 0 {
+			return nil
+		}
+		var labels RunsOn
+		if err := labels.UnmarshalYAML(&m.Labels); err != nil {
+			return err
+		}
+		r.Labels = labels.Labels
+		return nil
+	}
+	return fmt.Errorf("invalid runs-on field: %v", value.Value)
+}
+
+// String returns a compact, human readable representation of the runner.
+func (r RunsOn) String() string {
+	labels := strings.Join(r.Labels, ", ")
+	if r.Group == "" {
+		return labels
+	}
+	if labels == "" {
+		return r.Group
+	}
+	return r.Group + ": " + labels
+}
+
+// String returns a compact, human readable representation of the matrix: its expression, or its
+// dimensions in document order followed by the number of include and exclude entries.
+func (m Matrix) String() string {
+	if m.Expression != "" {
+		return m.Expression
+	}
+	var parts []string
+	for _, name := range m.DimensionOrder {
+		values := make([]string, 0, len(m.Dimensions[name]))
+		for _, value := range m.Dimensions[name] {
+			values = append(values, fmt.Sprint(value))
+		}
+		parts = append(parts, name+": "+strings.Join(values, ", "))
+	}
+	if len(m.Include) > 0 {
+		parts = append(parts, fmt.Sprintf("include: %d", len(m.Include)))
+	}
+	if len(m.Exclude) > 0 {
+		parts = append(parts, fmt.Sprintf("exclude: %d", len(m.Exclude)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// UnmarshalYAML custom unmarshal for Matrix to support expressions and dimension maps.
+func (m *Matrix) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		m.Expression = value.Value
+		return nil
+	case yaml.MappingNode:
+		m.Dimensions = map[string][]any{}
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key, val := value.Content[i].Value, value.Content[i+1]
+			switch key {
+			case "include":
+				if err := decodeMatrixEntries(val, &m.Include); err != nil {
+					return err
+				}
+			case "exclude":
+				if err := decodeMatrixEntries(val, &m.Exclude); err != nil {
+					return err
+				}
+			default:
+				var values []any
+				if val.Kind == yaml.ScalarNode {
+					values = []any{val.Value}
+				} else if err := val.Decode(&values); err != nil {
+					return fmt.Errorf("invalid matrix dimension %q: %w", key, err)
+				}
+				m.Dimensions[key] = values
+				m.DimensionOrder = append(m.DimensionOrder, key)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid matrix field: %v", value.Value)
+}
+
+// decodeMatrixEntries decodes matrix include/exclude lists; expressions are kept as a single entry.
+func decodeMatrixEntries(value *yaml.Node, out *[]map[string]any) error {
+	if value.Kind == yaml.ScalarNode {
+		*out = []map[string]any{{"expression": value.Value}}
+		return nil
+	}
+	return value.Decode(out)
+}
+
+// UnmarshalYAML custom unmarshal for Environment to support string or {name, url}.
+func (e *Environment) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Name = value.Value
+		return nil
+	}
+	type plain Environment
+	return value.Decode((*plain)(e))
+}
+
+// UnmarshalYAML custom unmarshal for Concurrency to support string or {group, cancel-in-progress}.
+func (c *Concurrency) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Group = value.Value
+		return nil
+	}
+	type plain Concurrency
+	return value.Decode((*plain)(c))
+}
+
+// UnmarshalYAML custom unmarshal for Permissions to support "read-all"/"write-all" or a scope map.
+func (p *Permissions) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		p.All = value.Value
+		return nil
+	}
+	return value.Decode(&p.Scopes)
+}
+
+// String returns a compact, human readable representation of the permissions: read-all or write-all,
+// the scopes in alphabetical order, or "none" for an empty scope map.
+func (p Permissions) String() string {
+	if p.All != "" {
+		return p.All
+	}
+	if len(p.Scopes) == 0 {
+		return "none"
+	}
+	scopes := make([]string, 0, len(p.Scopes))
+	for _, scope := range sortedKeys(p.Scopes) {
+		scopes = append(scopes, scope+": "+p.Scopes[scope])
+	}
+	return strings.Join(scopes, ", ")
+}
+
+// UnmarshalYAML custom unmarshal for Secrets to support "inherit" or a map of secrets.
+func (s *Secrets) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Value != "inherit" {
+			return fmt.Errorf("invalid secrets field: %v", value.Value)
+		}
+		s.Inherit = true
+		return nil
+	}
+	return value.Decode(&s.Values)
+}
+
+// String returns a compact, human readable representation of the secrets: inherit, or the names of the
+// secrets in alphabetical order.
+func (s Secrets) String() string {
+	if s.Inherit {
+		return "inherit"
+	}
+	return strings.Join(sortedKeys(s.Values), ", ")
+}
@@ -0,0 +1,130 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkflowYAML_FullJobSchema(t *testing.T) {
+	yamlData := []byte(`
+jobs:
+  build:
+    name: Build ${{ matrix.os }}
+    runs-on: ${{ matrix.os }}
+    if: github.event_name == 'push'
+    timeout-minutes: 30
+    environment:
+      name: production
+      url: https://example.com
+    concurrency:
+      group: build-${{ github.ref }}
+      cancel-in-progress: true
+    permissions:
+      contents: read
+      id-token: write
+    strategy:
+      fail-fast: false
+      max-parallel: 2
+      matrix:
+        os: [ubuntu-latest, windows-latest]
+        go: ["1.22", "1.23"]
+        include:
+          - os: macos-latest
+            go: "1.23"
+    outputs:
+      version: ${{ steps.version.outputs.value }}
+    steps:
+      - run: make
+  release:
+    uses: owner/repo/.github/workflows/release.yml@main
+    with:
+      dry-run: true
+    secrets: inherit
+    permissions: write-all
+    environment: staging
+    concurrency: release
+`)
+	wf, err := ParseWorkflowYAML("", yamlData)
+	assert.NoError(t, err)
+
+	build := wf.Jobs["build"]
+	assert.Equal(t, "Build ${{ matrix.os }}", build.Name)
+	assert.Equal(t, []string{"${{ matrix.os }}"}, build.RunsOn.Labels)
+	assert.Equal(t, "github.event_name == 'push'", build.If)
+	assert.Equal(t, "30", build.TimeoutMinutes)
+	assert.Equal(t, &Environment{Name: "production", URL: "https://example.com"}, build.Environment)
+	assert.Equal(t, &Concurrency{Group: "build-${{ github.ref }}", CancelInProgress: "true"}, build.Concurrency)
+	assert.Equal(t, map[string]string{"contents": "read", "id-token": "write"}, build.Permissions.Scopes)
+	assert.Equal(t, "false", build.Strategy.FailFast)
+	assert.Equal(t, "2", build.Strategy.MaxParallel)
+	assert.Equal(t, []string{"os", "go"}, build.Strategy.Matrix.DimensionOrder)
+	assert.Equal(t, []any{"ubuntu-latest", "windows-latest"}, build.Strategy.Matrix.Dimensions["os"])
+	assert.Equal(t, []map[string]any{{"os": "macos-latest", "go": "1.23"}}, build.Strategy.Matrix.Include)
+	assert.Equal(t, map[string]string{"version": "${{ steps.version.outputs.value }}"}, build.Outputs)
+
+	release := wf.Jobs["release"]
+	assert.Equal(t, map[string]any{"dry-run": true}, release.With)
+	assert.True(t, release.Secrets.Inherit)
+	assert.Equal(t, "write-all", release.Permissions.All)
+	assert.Equal(t, "staging", release.Environment.Name)
+	assert.Equal(t, "release", release.Concurrency.Group)
+
+	assert.Equal(t, "os: ubuntu-latest, windows-latest; go: 1.22, 1.23; include: 1", build.Strategy.Matrix.String())
+	assert.Equal(t, "contents: read, id-token: write", build.Permissions.String())
+	assert.Equal(t, "write-all", release.Permissions.String())
+	assert.Equal(t, "none", Permissions{Scopes: map[string]string{}}.String())
+	assert.Equal(t, "inherit", release.Secrets.String())
+	assert.Equal(t, "npm-token, token", Secrets{Values: map[string]string{"token": "${{ secrets.TOKEN }}", "npm-token": "${{ secrets.NPM }}"}}.String())
+}
+
+func TestRunsOn_Forms(t *testing.T) {
+	cases := []struct {
+		yaml   string
+		labels []string
+		group  string
+		str    string
+	}{
+		{yaml: "runs-on: ubuntu-latest", labels: []string{"ubuntu-latest"}, str: "ubuntu-latest"},
+		{yaml: "runs-on: [self-hosted, linux]", labels: []string{"self-hosted", "linux"}, str: "self-hosted, linux"},
+		{yaml: "runs-on: {group: large}", group: "large", str: "large"},
+		{yaml: "runs-on: {group: large, labels: gpu}", labels: []string{"gpu"}, group: "large", str: "large: gpu"},
+	}
+	for _, c := range cases {
+		wf, err := ParseWorkflowYAML("", []byte("jobs:\n  job:\n    "+c.yaml+"\n"))
+		assert.NoError(t, err, c.yaml)
+		runsOn := wf.Jobs["job"].RunsOn
+		assert.Equal(t, c.labels, runsOn.Labels, c.yaml)
+		assert.Equal(t, c.group, runsOn.Group, c.yaml)
+		assert.Equal(t, c.str, runsOn.String(), c.yaml)
+	}
+}
+
+func TestMatrix_Expression(t *testing.T) {
+	wf, err := ParseWorkflowYAML("", []byte(`
+jobs:
+  job:
+    strategy:
+      matrix: ${{ fromJSON(needs.setup.outputs.matrix) }}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "${{ fromJSON(needs.setup.outputs.matrix) }}", wf.Jobs["job"].Strategy.Matrix.Expression)
+}
+
+func TestSecrets_Invalid(t *testing.T) {
+	_, err := ParseWorkflowYAML("", []byte("jobs:\n  job:\n    secrets: everything\n"))
+	assert.Error(t, err)
+}
+
+func TestBuildUsesTree_JobDefinition(t *testing.T) {
+	wf := &Workflow{
+		Jobs: map[string]Job{
+			"build": {RunsOn: RunsOn{Labels: []string{"ubuntu-latest"}}, If: "always()"},
+		},
+	}
+	tree := BuildUsesTree(context.Background(), "root", wf, nil, 2)
+	assert.NotNil(t, tree.Children[0].Job)
+	assert.Equal(t, "ubuntu-latest", tree.Children[0].Job.RunsOn.String())
+	assert.Equal(t, "always()", tree.Children[0].Job.If)
+}
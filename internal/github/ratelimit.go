@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Defaults of the HTTP client.
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 3
+)
+
+const (
+	// initialBackoff is the wait before the first retry; it doubles on every attempt up to maxBackoff.
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+	// maxRateLimitWait is the longest wait for a rate limit reset before giving up.
+	maxRateLimitWait = time.Minute
+)
+
+// RateLimitError is returned when GitHub rejects a request because the rate limit is exhausted.
+type RateLimitError struct {
+	StatusCode int
+	Reset      time.Time // when the limit resets
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	msg := "GitHub API rate limit exceeded"
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf(", resets at %s", e.Reset.Format(time.RFC3339))
+	}
+	return msg + " (authenticate with a token to raise the limit)"
+}
+
+// rateLimitError returns a *RateLimitError if resp is a rate limited response, nil otherwise.
+// 429 responses are always rate limited; 403 responses only when the limit is exhausted or a
+// Retry-After is given (secondary rate limits), other 403s being permission errors.
+func (c *Client) rateLimitError(resp *http.Response) *RateLimitError {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	retryAfter := resp.Header.Get("Retry-After")
+	exhausted := resp.Header.Get("X-RateLimit-Remaining") == "0"
+	if resp.StatusCode == http.StatusForbidden && retryAfter == "" && !exhausted {
+		return nil
+	}
+
+	err := &RateLimitError{StatusCode: resp.StatusCode}
+	if retryAfter != "" {
+		if seconds, convErr := strconv.Atoi(retryAfter); convErr == nil {
+			err.Reset = c.now().Add(time.Duration(seconds) * time.Second)
+		} else if t, parseErr := http.ParseTime(retryAfter); parseErr == nil {
+			err.Reset = t
+		}
+	}
+	if err.Reset.IsZero() {
+		if reset, convErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); convErr == nil {
+			err.Reset = time.Unix(reset, 0)
+		}
+	}
+	if err.Reset.IsZero() {
+		err.Reset = c.now().Add(backoff(0))
+	}
+	return err
+}
+
+// sleep waits for d, returning early with the context error when ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns the wait before retrying after the given (0 based) attempt.
+func backoff(attempt int) time.Duration {
+	wait := initialBackoff << attempt
+	if wait <= 0 || wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
+
+// closeBody drains and closes the body of a response that is discarded before a retry.
+func closeBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if err := resp.Body.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "error closing response body: %v\n", err)
+	}
+}
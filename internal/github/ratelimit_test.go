@@ -0,0 +1,154 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scriptedClient returns a Client answering requests with the given responses in order (a nil response
+// is a network error), recording the waits between attempts.
+func scriptedClient(responses []*http.Response) (*Client, *[]time.Duration, *int) {
+	var waits []time.Duration
+	attempts := 0
+	now := time.Unix(1_700_000_000, 0)
+	client := NewClient("")
+	client.now = func() time.Time { return now }
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	client.httpClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := responses[min(attempts, len(responses)-1)]
+		attempts++
+		if resp == nil {
+			return nil, errors.New("connection reset")
+		}
+		return resp, nil
+	})}
+	return client, &waits, &attempts
+}
+
+func response(status int, headers map[string]string) *http.Response {
+	resp := &http.Response{StatusCode: status, Body: http.NoBody, Header: make(http.Header)}
+	for k, v := range headers {
+		resp.Header.Set(k, v)
+	}
+	return resp
+}
+
+func TestDo_RetriesServerAndNetworkErrors(t *testing.T) {
+	client, waits, attempts := scriptedClient([]*http.Response{
+		response(http.StatusBadGateway, nil),
+		nil,
+		response(http.StatusOK, nil),
+	})
+
+	req, _ := http.NewRequest("GET", "https://api.github.com/x", nil)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, *attempts)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, *waits)
+}
+
+func TestDo_GivesUpAfterMaxRetries(t *testing.T) {
+	client, _, attempts := scriptedClient([]*http.Response{response(http.StatusServiceUnavailable, nil)})
+	client.SetMaxRetries(2)
+
+	req, _ := http.NewRequest("GET", "https://api.github.com/x", nil)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 3, *attempts)
+
+	client, _, _ = scriptedClient([]*http.Response{nil})
+	client.SetMaxRetries(0)
+	_, err = client.Do(req)
+	assert.Error(t, err)
+}
+
+func TestDo_HonoursRetryAfter(t *testing.T) {
+	client, waits, _ := scriptedClient([]*http.Response{
+		response(http.StatusTooManyRequests, map[string]string{"Retry-After": "7"}),
+		response(http.StatusOK, nil),
+	})
+
+	req, _ := http.NewRequest("GET", "https://api.github.com/x", nil)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{7 * time.Second}, *waits)
+}
+
+func TestDo_HonoursRateLimitReset(t *testing.T) {
+	reset := strconv.FormatInt(1_700_000_000+20, 10)
+	client, waits, _ := scriptedClient([]*http.Response{
+		response(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}),
+		response(http.StatusOK, nil),
+	})
+
+	req, _ := http.NewRequest("GET", "https://api.github.com/x", nil)
+	_, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{20 * time.Second}, *waits)
+}
+
+func TestDo_RateLimitExhausted(t *testing.T) {
+	reset := strconv.FormatInt(1_700_000_000+3600, 10)
+	client, waits, attempts := scriptedClient([]*http.Response{
+		response(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}),
+	})
+
+	req, _ := http.NewRequest("GET", "https://api.github.com/x", nil)
+	_, err := client.Do(req)
+	var rateLimitErr *RateLimitError
+	assert.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, time.Unix(1_700_003_600, 0), rateLimitErr.Reset)
+	assert.Contains(t, err.Error(), "rate limit exceeded")
+	assert.Equal(t, 1, *attempts)
+	assert.Empty(t, *waits)
+}
+
+func TestDo_ForbiddenIsNotRetried(t *testing.T) {
+	client, _, attempts := scriptedClient([]*http.Response{
+		response(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "42"}),
+	})
+
+	req, _ := http.NewRequest("GET", "https://api.github.com/x", nil)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, 1, *attempts)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 500*time.Millisecond, backoff(0))
+	assert.Equal(t, 4*time.Second, backoff(3))
+	assert.Equal(t, maxBackoff, backoff(10))
+	assert.Equal(t, maxBackoff, backoff(100))
+}
+
+func TestSetTimeout(t *testing.T) {
+	client := NewClient("")
+	assert.Equal(t, DefaultTimeout, client.httpClient.Timeout)
+	client.SetTimeout(time.Second)
+	assert.Equal(t, time.Second, client.httpClient.Timeout)
+}
+
+func TestDo_CanceledDuringBackoff(t *testing.T) {
+	client, _, attempts := scriptedClient([]*http.Response{response(http.StatusBadGateway, nil)})
+	client.sleep = sleep
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.github.com/x", nil)
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, *attempts)
+}
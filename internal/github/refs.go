@@ -0,0 +1,178 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of git references a 'uses' ref can point at.
+const (
+	RefKindBranch = "branch"
+	RefKindTag    = "tag"
+	RefKindCommit = "commit"
+)
+
+// ResolvedRef is a git reference resolved to the commit it points at.
+type ResolvedRef struct {
+	SHA  string `json:"sha"`
+	Kind string `json:"kind"` // one of the RefKind constants
+}
+
+// ContentsFetcher defines the interface for resolving references and reading files through the GitHub API.
+type ContentsFetcher interface {
+	ResolveRef(ctx context.Context, owner, repo, ref string) (*ResolvedRef, error)
+	GetContents(ctx context.Context, owner, repo, path, ref string) ([]byte, error)
+}
+
+// gitRef is the part of a git refs or git tags API response that is needed to resolve a reference.
+type gitRef struct {
+	Object struct {
+		SHA  string `json:"sha"`
+		Type string `json:"type"`
+	} `json:"object"`
+}
+
+// ResolveRef resolves a branch, tag or commit SHA of owner/repo to a commit SHA using the git refs API.
+// Branches take precedence over tags with the same name, as they do for GitHub Actions.
+func (c *Client) ResolveRef(ctx context.Context, owner, repo, ref string) (*ResolvedRef, error) {
+	if isCommitSHA(ref) {
+		return &ResolvedRef{SHA: ref, Kind: RefKindCommit}, nil
+	}
+
+	slog.Debug("Resolving reference", "owner", owner, "repo", repo, "ref", ref)
+
+	for _, kind := range []string{RefKindBranch, RefKindTag} {
+		prefix := "heads"
+		if kind == RefKindTag {
+			prefix = "tags"
+		}
+		var r gitRef
+		err := c.getJSON(ctx, fmt.Sprintf("/repos/%s/%s/git/ref/%s/%s", owner, repo, prefix, escapePath(ref)), &r)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Annotated tags point at a tag object, which in turn points at the commit.
+		if r.Object.Type == "tag" {
+			if err := c.getJSON(ctx, fmt.Sprintf("/repos/%s/%s/git/tags/%s", owner, repo, r.Object.SHA), &r); err != nil {
+				return nil, err
+			}
+		}
+		return &ResolvedRef{SHA: r.Object.SHA, Kind: kind}, nil
+	}
+	return nil, fmt.Errorf("reference %s not found in %s/%s: %w", ref, owner, repo, &StatusError{StatusCode: http.StatusNotFound})
+}
+
+// GetContents returns the raw content of the file at path in owner/repo at ref using the contents API.
+func (c *Client) GetContents(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	p := fmt.Sprintf("/repos/%s/%s/contents/%s?ref=%s", owner, repo, escapePath(path), url.QueryEscape(ref))
+
+	slog.Debug("Downloading contents", "owner", owner, "repo", repo, "path", path, "ref", ref)
+
+	return c.get(ctx, p, "application/vnd.github.raw+json")
+}
+
+// getJSON performs a GET request on an API path and decodes the JSON response into v.
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	data, err := c.get(ctx, path, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// fetchContents resolves ar with the GitHub API and downloads the workflow or action it references at the
+// resolved commit. Returns a *ResolveError when the reference or file does not exist or cannot be parsed;
+// other errors are returned as is so that the caller can fall back to raw URLs.
+func fetchContents(ctx context.Context, fetcher ContentsFetcher, host string, ar ActionRef) (*Workflow, error) {
+	resolved, err := fetcher.ResolveRef(ctx, ar.Owner, ar.Repo, ar.Ref)
+	if isNotFound(err) {
+		slog.Warn("Reference not found", "uses", ar.Raw, "ref", ar.Ref)
+		return nil, &ResolveError{Uses: ar.Raw, Reason: UnresolvedNotFound, Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	ar.SHA = resolved.SHA
+	ar.RefKind = resolved.Kind
+
+	candidates := documentPaths(ar, strings.Trim(ar.Path, "/"))
+	var parseErr error
+	for _, candidate := range candidates {
+		data, err := fetcher.GetContents(ctx, ar.Owner, ar.Repo, candidate, ar.SHA)
+		if isNotFound(err) {
+			slog.Debug("File not found", "owner", ar.Owner, "repo", ar.Repo, "path", candidate, "sha", ar.SHA)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		u := rawContentURL(host, ar.Owner, ar.Repo, ar.SHA, candidate)
+		wf, err := parseActionDocument(u, data, ar)
+		if err == nil {
+			return wf, nil
+		}
+		parseErr = err
+	}
+	if parseErr != nil {
+		return nil, &ResolveError{Uses: ar.Raw, Reason: UnresolvedParse, Err: parseErr}
+	}
+	slog.Warn("No workflow or action found for reference", "uses", ar.Raw, "candidates", candidates)
+	return nil, &ResolveError{Uses: ar.Raw, Reason: UnresolvedNotFound}
+}
+
+// MutableRefs returns the distinct 'uses' references of the tree that point at a branch, sorted.
+// Branches can move, so these references are not reproducible.
+func MutableRefs(root *UsesNode) []string {
+	seen := map[string]bool{}
+	var refs []string
+	var visit func(n *UsesNode)
+	visit = func(n *UsesNode) {
+		// Local references follow the commit of their workflow, whatever the ref it was read from.
+		if n.Ref != nil && n.Ref.Type != UsesTypeLocal && n.Ref.RefKind == RefKindBranch && !seen[n.Ref.Raw] {
+			seen[n.Ref.Raw] = true
+			refs = append(refs, n.Ref.Raw)
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	if root != nil {
+		visit(root)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// isCommitSHA reports whether ref is a full commit SHA.
+func isCommitSHA(ref string) bool {
+	return regexp.MustCompile(`^[0-9a-f]{40}$`).MatchString(ref)
+}
+
+// isNotFound reports whether err is an HTTP 404 StatusError.
+func isNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// escapePath escapes each segment of a slash separated path.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
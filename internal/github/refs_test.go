@@ -0,0 +1,144 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	commitSHA = "0123456789abcdef0123456789abcdef01234567"
+	tagSHA    = "fedcba9876543210fedcba9876543210fedcba98"
+)
+
+// apiClient returns a Client answering API paths from responses; other paths get a 404.
+func apiClient(t *testing.T, responses map[string]string) *Client {
+	client := NewClient("token")
+	client.httpClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.True(t, strings.HasPrefix(req.URL.String(), baseURL+"/"), req.URL.String())
+		body, ok := responses[strings.TrimPrefix(req.URL.String(), baseURL)]
+		if !ok {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Header: make(http.Header)}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+	return client
+}
+
+func TestResolveRef(t *testing.T) {
+	client := apiClient(t, map[string]string{
+		"/repos/owner/repo/git/ref/heads/main":     `{"object": {"sha": "` + commitSHA + `", "type": "commit"}}`,
+		"/repos/owner/repo/git/ref/tags/v1":        `{"object": {"sha": "` + tagSHA + `", "type": "tag"}}`,
+		"/repos/owner/repo/git/tags/" + tagSHA:     `{"object": {"sha": "` + commitSHA + `", "type": "commit"}}`,
+		"/repos/owner/repo/git/ref/tags/light":     `{"object": {"sha": "` + commitSHA + `", "type": "commit"}}`,
+		"/repos/owner/repo/git/ref/heads/feat/foo": `{"object": {"sha": "` + commitSHA + `", "type": "commit"}}`,
+	})
+
+	tests := []struct {
+		ref  string
+		kind string
+	}{
+		{"main", RefKindBranch},
+		{"feat/foo", RefKindBranch},
+		{"v1", RefKindTag},
+		{"light", RefKindTag},
+		{commitSHA, RefKindCommit},
+	}
+	for _, tt := range tests {
+		resolved, err := client.ResolveRef(context.Background(), "owner", "repo", tt.ref)
+		assert.NoError(t, err, tt.ref)
+		assert.Equal(t, &ResolvedRef{SHA: commitSHA, Kind: tt.kind}, resolved, tt.ref)
+	}
+
+	_, err := client.ResolveRef(context.Background(), "owner", "repo", "missing")
+	assert.Error(t, err)
+	assert.True(t, isNotFound(err))
+}
+
+func TestGetContents(t *testing.T) {
+	client := NewClient("token")
+	client.httpClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "https://api.github.com/repos/owner/repo/contents/.github/workflows/ci.yml?ref="+commitSHA, req.URL.String())
+		assert.Equal(t, "application/vnd.github.raw+json", req.Header.Get("Accept"))
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("jobs: {}")), Header: make(http.Header)}, nil
+	})}
+
+	data, err := client.GetContents(context.Background(), "owner", "repo", ".github/workflows/ci.yml", commitSHA)
+	assert.NoError(t, err)
+	assert.Equal(t, "jobs: {}", string(data))
+}
+
+func TestFetchActionWorkflow_ContentsAPI(t *testing.T) {
+	client := apiClient(t, map[string]string{
+		"/repos/owner/repo/git/ref/heads/main":                                    `{"object": {"sha": "` + commitSHA + `", "type": "commit"}}`,
+		"/repos/owner/repo/contents/.github/workflows/build.yml?ref=" + commitSHA: "jobs:\n  build:\n    runs-on: ubuntu-latest\n",
+		"/repos/owner/repo/contents/setup/action.yaml?ref=" + commitSHA:           "name: Setup\nruns:\n  using: node20\n  main: index.js\n",
+	})
+
+	ar, _ := ParseActionRef("owner/repo/.github/workflows/build.yml@main", Repository{})
+	wf, err := FetchActionWorkflow(context.Background(), client, ar)
+	assert.NoError(t, err)
+	assert.Equal(t, commitSHA, wf.Ref.SHA)
+	assert.Equal(t, RefKindBranch, wf.Ref.RefKind)
+	assert.Equal(t, "https://raw.githubusercontent.com/owner/repo/"+commitSHA+"/.github/workflows/build.yml", wf.URL)
+
+	ar, _ = ParseActionRef("owner/repo/setup@main", Repository{})
+	wf, err = FetchActionWorkflow(context.Background(), client, ar)
+	assert.NoError(t, err)
+	assert.Equal(t, "Setup", wf.Name)
+	assert.NotNil(t, wf.Action)
+
+	ar, _ = ParseActionRef("owner/repo/setup@missing", Repository{})
+	wf, err = FetchActionWorkflow(context.Background(), client, ar)
+	assert.Nil(t, wf)
+	var resolveErr *ResolveError
+	assert.ErrorAs(t, err, &resolveErr)
+	assert.Equal(t, UnresolvedNotFound, resolveErr.Reason)
+}
+
+// failingContentsClient fails every API call, forcing the raw URL fallback.
+type failingContentsClient struct {
+	mockClient
+}
+
+func (c *failingContentsClient) ResolveRef(ctx context.Context, owner, repo, ref string) (*ResolvedRef, error) {
+	return nil, errors.New("rate limited")
+}
+
+func (c *failingContentsClient) GetContents(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	return nil, errors.New("rate limited")
+}
+
+func TestFetchActionWorkflow_FallsBackToRawURLs(t *testing.T) {
+	client := &failingContentsClient{mockClient{DownloadWorkflowFunc: func(url string) ([]byte, error) {
+		if url == "https://raw.githubusercontent.com/owner/repo/refs/tags/v1/setup/action.yml" {
+			return []byte("name: Setup\nruns:\n  using: node20\n  main: index.js\n"), nil
+		}
+		return nil, &StatusError{StatusCode: http.StatusNotFound}
+	}}}
+
+	ar, _ := ParseActionRef("owner/repo/setup@v1", Repository{})
+	wf, err := FetchActionWorkflow(context.Background(), client, ar)
+	assert.NoError(t, err)
+	assert.Equal(t, RefKindTag, wf.Ref.RefKind)
+	assert.Empty(t, wf.Ref.SHA)
+}
+
+func TestMutableRefs(t *testing.T) {
+	branch := &ActionRef{Raw: "owner/repo/a.yml@main", RefKind: RefKindBranch}
+	root := &UsesNode{Children: []*UsesNode{
+		{Ref: branch, Children: []*UsesNode{{Ref: branch}}},
+		{Ref: &ActionRef{Raw: "owner/repo/b@v1", RefKind: RefKindTag}},
+		{Ref: &ActionRef{Raw: "owner/repo/c@" + commitSHA, RefKind: RefKindCommit}},
+		{Ref: &ActionRef{Raw: "other/repo/d@dev", RefKind: RefKindBranch}},
+		{Ref: &ActionRef{Raw: "./actions/setup", Type: UsesTypeLocal, RefKind: RefKindBranch}},
+	}}
+
+	assert.Equal(t, []string{"other/repo/d@dev", "owner/repo/a.yml@main"}, MutableRefs(root))
+	assert.Nil(t, MutableRefs(nil))
+}
@@ -0,0 +1,159 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// WorkflowsDir is the directory where GitHub looks for workflow files.
+const WorkflowsDir = ".github/workflows"
+
+// Repository is the repository that local ('./') references are resolved against: owner/repo at a ref for
+// remote workflows, or a directory of the filesystem for local ones.
+type Repository struct {
+	Owner string
+	Name  string
+	Ref   string
+	Dir   string // root directory of a local repository, "" for the current directory
+}
+
+// IsRemote reports whether r is a remote repository.
+func (r Repository) IsRemote() bool {
+	return r.Owner != "" && r.Name != "" && r.Ref != ""
+}
+
+// LocalRepositoryRoot returns the root directory of the repository containing the local workflow file:
+// the parent of its .github/workflows directory, or the closest parent directory holding a .git entry.
+// Returns "" when the file does not exist or is not in a repository.
+func LocalRepositoryRoot(workflowPath string) string {
+	abs, err := filepath.Abs(strings.TrimPrefix(workflowPath, "file://"))
+	if err != nil {
+		return ""
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return ""
+	}
+	dir := filepath.Dir(abs)
+	if filepath.ToSlash(dir) == WorkflowsDir || strings.HasSuffix(filepath.ToSlash(dir), "/"+WorkflowsDir) {
+		return filepath.Dir(filepath.Dir(dir))
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// WorkflowLister defines the interface for listing the workflow files of a remote repository.
+type WorkflowLister interface {
+	ListWorkflows(ctx context.Context, owner, repo, ref string) ([]string, error)
+}
+
+// contentEntry is an item returned by the GitHub contents API for a directory.
+type contentEntry struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	DownloadURL string `json:"download_url"`
+}
+
+// ListWorkflows lists the workflow files of owner/repo at ref using the GitHub contents API
+// and returns their download URLs. An empty ref means the repository default branch.
+func (c *Client) ListWorkflows(ctx context.Context, owner, repo, ref string) ([]string, error) {
+	p := fmt.Sprintf("/repos/%s/%s/contents/%s", owner, repo, WorkflowsDir)
+	if ref != "" {
+		p += "?ref=" + url.QueryEscape(ref)
+	}
+	slog.Debug("Listing repository workflows", "owner", owner, "repo", repo, "ref", ref)
+
+	data, err := c.get(ctx, p, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []contentEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse contents response: %w", err)
+	}
+	var urls []string
+	for _, entry := range entries {
+		if entry.Type == "file" && isWorkflowFile(entry.Name) {
+			urls = append(urls, entry.DownloadURL)
+		}
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+// ListLocalWorkflows returns the workflow files found in dir, sorted by name.
+// dir may be either a repository root (containing .github/workflows) or the workflows directory itself.
+func ListLocalWorkflows(dir string) ([]string, error) {
+	if info, err := os.Stat(filepath.Join(dir, WorkflowsDir)); err == nil && info.IsDir() {
+		dir = filepath.Join(dir, WorkflowsDir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflows directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isWorkflowFile(entry.Name()) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// ParseRepositoryRef parses an owner/repo[@ref] string.
+// Returns ok=false if the string does not look like a repository reference, e.g. when it looks like a
+// relative path (./dir, ../dir or a YAML file).
+func ParseRepositoryRef(s string) (owner, repo, ref string, ok bool) {
+	re := regexp.MustCompile(`^([A-Za-z0-9_.-]+)/([A-Za-z0-9_.-]+)(?:@(.+))?$`)
+	matches := re.FindStringSubmatch(s)
+	if len(matches) != 4 || strings.HasPrefix(matches[1], ".") ||
+		strings.Contains(matches[1]+"/"+matches[2], ".yml") || strings.Contains(matches[1]+"/"+matches[2], ".yaml") {
+		return "", "", "", false
+	}
+	return matches[1], matches[2], matches[3], true
+}
+
+// WorkflowFileName returns the file name of the workflow referenced by a job-level 'uses'
+// when it points at a workflow of the repository owner/repo (either "./.github/workflows/x.yml"
+// or "owner/repo/.github/workflows/x.yml@ref"). Returns "" otherwise.
+func WorkflowFileName(uses, owner, repo string) string {
+	p := uses
+	if !strings.HasPrefix(uses, "./") {
+		prefix := owner + "/" + repo + "/"
+		if owner == "" || !strings.HasPrefix(uses, prefix) {
+			return ""
+		}
+		p = strings.TrimPrefix(uses, prefix)
+		if i := strings.Index(p, "@"); i >= 0 {
+			p = p[:i]
+		}
+	}
+	p = strings.TrimPrefix(p, "./")
+	if path.Dir(p) != WorkflowsDir || !isWorkflowFile(p) {
+		return ""
+	}
+	return path.Base(p)
+}
+
+// isWorkflowFile reports whether name has a YAML extension.
+func isWorkflowFile(name string) bool {
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+}
@@ -0,0 +1,170 @@
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListLocalWorkflows(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, ".github", "workflows")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0o755))
+	for _, name := range []string{"b.yml", "a.yaml", "README.md"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("jobs: {}"), 0o644))
+	}
+
+	files, err := ListLocalWorkflows(root)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yml")}, files)
+
+	files, err = ListLocalWorkflows(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	_, err = ListLocalWorkflows(filepath.Join(root, "missing"))
+	assert.Error(t, err)
+}
+
+func TestListWorkflows(t *testing.T) {
+	client := NewClient("token")
+	client.httpClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "https://api.github.com/repos/owner/repo/contents/.github/workflows?ref=v1", req.URL.String())
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		body := `[
+			{"name": "release.yml", "type": "file", "download_url": "https://raw.githubusercontent.com/owner/repo/v1/.github/workflows/release.yml"},
+			{"name": "ci.yaml", "type": "file", "download_url": "https://raw.githubusercontent.com/owner/repo/v1/.github/workflows/ci.yaml"},
+			{"name": "notes.md", "type": "file", "download_url": "https://raw.githubusercontent.com/owner/repo/v1/.github/workflows/notes.md"},
+			{"name": "dir", "type": "dir"}
+		]`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+
+	urls, err := client.ListWorkflows(context.Background(), "owner", "repo", "v1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://raw.githubusercontent.com/owner/repo/v1/.github/workflows/ci.yaml",
+		"https://raw.githubusercontent.com/owner/repo/v1/.github/workflows/release.yml",
+	}, urls)
+}
+
+func TestListWorkflows_ErrorStatus(t *testing.T) {
+	client := NewClient("")
+	client.httpClient = &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "https://api.github.com/repos/owner/repo/contents/.github/workflows", req.URL.String())
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Header: make(http.Header)}, nil
+	})}
+
+	_, err := client.ListWorkflows(context.Background(), "owner", "repo", "")
+	assert.Error(t, err)
+}
+
+func TestParseRepositoryRef(t *testing.T) {
+	cases := []struct {
+		input string
+		owner string
+		repo  string
+		ref   string
+		ok    bool
+	}{
+		{input: "owner/repo", owner: "owner", repo: "repo", ok: true},
+		{input: "owner/repo@v1.2", owner: "owner", repo: "repo", ref: "v1.2", ok: true},
+		{input: "owner/repo@feature/x", owner: "owner", repo: "repo", ref: "feature/x", ok: true},
+		{input: "workflows/ci.yml"},
+		{input: "owner/repo/path"},
+		{input: "./repo"},
+		{input: "../repo"},
+		{input: ".github/workflows"},
+		{input: "ci.yml/dir"},
+		{input: "dir/ci.yaml@main"},
+		{input: "https://github.com/owner/repo"},
+	}
+	for _, c := range cases {
+		owner, repo, ref, ok := ParseRepositoryRef(c.input)
+		assert.Equal(t, c.ok, ok, c.input)
+		assert.Equal(t, c.owner, owner, c.input)
+		assert.Equal(t, c.repo, repo, c.input)
+		assert.Equal(t, c.ref, ref, c.input)
+	}
+}
+
+func TestWorkflowFileName(t *testing.T) {
+	cases := []struct {
+		uses string
+		want string
+	}{
+		{uses: "./.github/workflows/build.yml", want: "build.yml"},
+		{uses: "owner/repo/.github/workflows/build.yaml@main", want: "build.yaml"},
+		{uses: "other/repo/.github/workflows/build.yml@main", want: ""},
+		{uses: "./.github/actions/setup", want: ""},
+		{uses: "actions/checkout@v4", want: ""},
+		{uses: "", want: ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, WorkflowFileName(c.uses, "owner", "repo"), c.uses)
+	}
+}
+
+func TestLocalRepositoryRoot(t *testing.T) {
+	root := t.TempDir()
+	workflows := filepath.Join(root, ".github", "workflows")
+	assert.NoError(t, os.MkdirAll(workflows, 0o755))
+	ci := filepath.Join(workflows, "ci.yml")
+	assert.NoError(t, os.WriteFile(ci, []byte("jobs: {}"), 0o644))
+	assert.Equal(t, root, LocalRepositoryRoot(ci))
+	assert.Equal(t, root, LocalRepositoryRoot("file://"+ci))
+
+	// Outside of .github/workflows, the closest directory with a .git entry is the root.
+	nested := filepath.Join(root, "ci", "workflows")
+	assert.NoError(t, os.MkdirAll(nested, 0o755))
+	assert.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o755))
+	other := filepath.Join(nested, "other.yml")
+	assert.NoError(t, os.WriteFile(other, []byte("jobs: {}"), 0o644))
+	assert.Equal(t, root, LocalRepositoryRoot(other))
+
+	assert.Empty(t, LocalRepositoryRoot(filepath.Join(root, "missing.yml")))
+}
+
+func TestFetchActionWorkflow_LocalRepository(t *testing.T) {
+	root := t.TempDir()
+	action := filepath.Join(root, "actions", "setup")
+	assert.NoError(t, os.MkdirAll(action, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(action, "action.yml"), []byte("name: Setup\nruns:\n  using: node20\n  main: index.js\n"), 0o644))
+
+	ar, err := ParseActionRef("./actions/setup", Repository{Dir: root})
+	assert.NoError(t, err)
+	assert.Equal(t, root, ar.Dir)
+	wf, err := FetchActionWorkflow(context.Background(), NewClient(""), ar)
+	assert.NoError(t, err)
+	assert.Equal(t, "Setup", wf.Name)
+	assert.Equal(t, filepath.Join(action, "action.yml"), wf.URL)
+}
+
+func TestFetchActionWorkflow_LocalInRemoteRepository(t *testing.T) {
+	var requested []string
+	client := &mockClient{DownloadWorkflowFunc: func(url string) ([]byte, error) {
+		requested = append(requested, url)
+		if url == "https://raw.githubusercontent.com/owner/repo/refs/heads/main/.github/workflows/build.yml" {
+			return []byte("jobs:\n  compile: {}\n"), nil
+		}
+		return nil, &StatusError{StatusCode: http.StatusNotFound}
+	}}
+
+	ar, err := ParseActionRef("./.github/workflows/build.yml", Repository{Owner: "owner", Name: "repo", Ref: "main", Dir: "ignored"})
+	assert.NoError(t, err)
+	assert.Equal(t, ActionRef{Type: UsesTypeLocal, Target: UsesTargetWorkflow, Owner: "owner", Repo: "repo", Ref: "main",
+		Path: "./.github/workflows/build.yml", Raw: "./.github/workflows/build.yml"}, ar)
+
+	wf, err := FetchActionWorkflow(context.Background(), client, ar)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://raw.githubusercontent.com/owner/repo/refs/heads/main/.github/workflows/build.yml"}, requested)
+	assert.Equal(t, UsesTypeLocal, wf.Ref.Type)
+	assert.Equal(t, "./.github/workflows/build.yml", wf.Ref.Path)
+	assert.Equal(t, RefKindBranch, wf.Ref.RefKind)
+}
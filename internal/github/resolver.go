@@ -0,0 +1,184 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultConcurrency is the default number of references fetched in parallel.
+const DefaultConcurrency = 8
+
+// Resolver fetches the workflows and actions referenced by ActionRefs. Results are memoized, concurrent
+// requests for the same reference share a single download, and at most `concurrency` references are
+// downloaded at the same time. It is safe for concurrent use.
+type Resolver struct {
+	client WorkflowDownloader
+	sem    chan struct{}
+
+	mu    sync.Mutex
+	calls map[string]*resolveCall
+}
+
+// resolveCall is a fetch in flight or completed.
+type resolveCall struct {
+	done chan struct{}
+	wf   *Workflow
+	err  error
+}
+
+// NewResolver creates a Resolver downloading with client, with at most concurrency downloads in flight.
+func NewResolver(client WorkflowDownloader, concurrency int) *Resolver {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Resolver{
+		client: client,
+		sem:    make(chan struct{}, concurrency),
+		calls:  make(map[string]*resolveCall),
+	}
+}
+
+// Resolve returns the workflow or action referenced by ar, or an error if it cannot be fetched or ctx is
+// done before it is.
+func (r *Resolver) Resolve(ctx context.Context, ar ActionRef) (*Workflow, error) {
+	key := fmt.Sprintf("%s|%s|%s|%s|%s|%s", ar.Type, ar.Owner, ar.Repo, ar.Ref, ar.Dir, ar.Path)
+
+	r.mu.Lock()
+	if call, ok := r.calls[key]; ok {
+		r.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.wf, call.err
+		case <-ctx.Done():
+			return nil, newResolveError(ar.Raw, ctx.Err())
+		}
+	}
+	call := &resolveCall{done: make(chan struct{})}
+	r.calls[key] = call
+	r.mu.Unlock()
+
+	select {
+	case r.sem <- struct{}{}:
+		call.wf, call.err = FetchActionWorkflow(ctx, r.client, ar)
+		<-r.sem
+	case <-ctx.Done():
+		call.err = newResolveError(ar.Raw, ctx.Err())
+	}
+	close(call.done)
+
+	return call.wf, call.err
+}
+
+// Prefetch concurrently fetches every reference that BuildUsesTree would fetch for wf with the same depth,
+// so that the (serial, deterministic) tree traversal is then served from the fetcher memo. At most
+// concurrency references are fetched at the same time.
+// fetcher must be safe for concurrent use, e.g. backed by a Resolver.
+func Prefetch(ctx context.Context, wf *Workflow, fetcher func(context.Context, string) (*Workflow, error), depth, concurrency int) {
+	p := NewPrefetcher(concurrency)
+	p.Add(ctx, wf, fetcher, depth)
+	p.Wait()
+}
+
+// Prefetcher fetches the references of several workflows with a bounded pool of workers: references found
+// in fetched documents are queued rather than fetched by new goroutines. Like BuildUsesTree (see
+// documentGraph), the references of a workflow or action are only walked again when it is reached with a
+// larger depth.
+type Prefetcher struct {
+	concurrency int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []prefetchTask
+	pending int            // queued or running tasks
+	walked  map[string]int // depth the references of each document were queued for, by documentKey
+}
+
+// prefetchTask is the fetch of a reference, and of its own references.
+type prefetchTask struct {
+	ctx     context.Context
+	uses    string
+	fetcher func(context.Context, string) (*Workflow, error)
+	depth   int
+}
+
+// NewPrefetcher creates a Prefetcher with at most concurrency references fetched at the same time.
+func NewPrefetcher(concurrency int) *Prefetcher {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	p := &Prefetcher{concurrency: concurrency, walked: map[string]int{}}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Add queues the references that BuildUsesTree would fetch for wf with the same depth.
+func (p *Prefetcher) Add(ctx context.Context, wf *Workflow, fetcher func(context.Context, string) (*Workflow, error), depth int) {
+	if wf != nil {
+		p.workflow(ctx, wf, fetcher, depth)
+	}
+}
+
+// Wait fetches the queued references, and the ones found in the fetched documents, and returns once
+// they are all fetched.
+func (p *Prefetcher) Wait() {
+	var wg sync.WaitGroup
+	for range p.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work()
+		}()
+	}
+	wg.Wait()
+}
+
+// work runs queued tasks until there are none left, queued or running.
+func (p *Prefetcher) work() {
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && p.pending > 0 {
+			p.cond.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		task := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		if childWf, _ := task.fetcher(task.ctx, task.uses); childWf != nil {
+			p.workflow(task.ctx, childWf, task.fetcher, task.depth-1)
+		}
+
+		p.mu.Lock()
+		p.pending--
+		if p.pending == 0 {
+			p.cond.Broadcast()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// workflow mirrors the fetches done by documentGraph.add: it queues the fetch of the references of wf,
+// unless they were already queued for that depth.
+func (p *Prefetcher) workflow(ctx context.Context, wf *Workflow, fetcher func(context.Context, string) (*Workflow, error), depth int) {
+	if depth <= 1 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key := documentKey(wf); key != "" {
+		if depth <= p.walked[key] {
+			return
+		}
+		p.walked[key] = depth
+	}
+	fetcher = fetcherOf(wf, fetcher)
+	for _, uses := range documentUses(wf) {
+		p.queue = append(p.queue, prefetchTask{ctx: ctx, uses: uses, fetcher: fetcher, depth: depth})
+		p.pending++
+	}
+	p.cond.Broadcast()
+}
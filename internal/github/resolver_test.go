@@ -0,0 +1,163 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowDownloader serves files from a map after a short delay, counting calls and the peak of calls in flight.
+type slowDownloader struct {
+	files map[string]string
+
+	mu          sync.Mutex
+	calls       map[string]int
+	inFlight    int
+	maxInFlight int
+}
+
+func (d *slowDownloader) DownloadWorkflow(ctx context.Context, url string) ([]byte, error) {
+	d.mu.Lock()
+	if d.calls == nil {
+		d.calls = map[string]int{}
+	}
+	d.calls[url]++
+	d.inFlight++
+	d.maxInFlight = max(d.maxInFlight, d.inFlight)
+	d.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	d.mu.Lock()
+	d.inFlight--
+	d.mu.Unlock()
+
+	content, ok := d.files[url]
+	if !ok {
+		return nil, fmt.Errorf("not found: %s", url)
+	}
+	return []byte(content), nil
+}
+
+func (d *slowDownloader) total() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, c := range d.calls {
+		n += c
+	}
+	return n
+}
+
+func TestResolver_DeduplicatesConcurrentRequests(t *testing.T) {
+	client := &slowDownloader{files: map[string]string{
+		"./reusable.yml": "jobs:\n  build:\n    runs-on: ubuntu-latest\n",
+	}}
+	resolver := NewResolver(client, 4)
+	ar := ActionRef{Type: "local", Path: "./reusable.yml"}
+
+	var wg sync.WaitGroup
+	results := make([]*Workflow, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = resolver.Resolve(context.Background(), ar)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, client.calls["./reusable.yml"])
+	for _, wf := range results {
+		assert.Same(t, results[0], wf)
+	}
+}
+
+func TestResolver_MemoizesMisses(t *testing.T) {
+	client := &slowDownloader{files: map[string]string{}}
+	resolver := NewResolver(client, 1)
+	ar := ActionRef{Type: "local", Path: "./missing"}
+
+	_, err := resolver.Resolve(context.Background(), ar)
+	assert.Error(t, err)
+	_, err = resolver.Resolve(context.Background(), ar)
+	assert.Error(t, err)
+	assert.Equal(t, 1, client.calls["./missing/action.yml"])
+}
+
+func TestPrefetch_BoundedAndDeterministic(t *testing.T) {
+	files := map[string]string{}
+	root := "jobs:\n"
+	for i := range 6 {
+		root += fmt.Sprintf("  job%d:\n    uses: ./.github/workflows/wf%d.yml\n", i, i)
+		files[fmt.Sprintf("./.github/workflows/wf%d.yml", i)] = "jobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: ./action\n"
+	}
+	files["./action/action.yml"] = "name: Shared\nruns:\n  using: node20\n  main: index.js\n"
+	wf, err := ParseWorkflowYAML("root.yml", []byte(root))
+	assert.NoError(t, err)
+
+	client := &slowDownloader{files: files}
+	resolver := NewResolver(client, 2)
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		ar, _ := ParseActionRef(uses, Repository{})
+		return resolver.Resolve(ctx, ar)
+	}
+
+	Prefetch(context.Background(), wf, fetcher, 2, DefaultConcurrency)
+	assert.LessOrEqual(t, client.maxInFlight, 2)
+	for i := range 6 {
+		assert.Equal(t, 1, client.calls[fmt.Sprintf("./.github/workflows/wf%d.yml", i)])
+	}
+
+	// The tree is built from the memo and matches a purely serial resolution.
+	before := client.total()
+	tree := BuildUsesTree(context.Background(), "workflow", wf, fetcher, 2)
+	assert.Equal(t, before, client.total())
+
+	serial := NewResolver(&slowDownloader{files: files}, 1)
+	expected := BuildUsesTree(context.Background(), "workflow", wf, func(ctx context.Context, uses string) (*Workflow, error) {
+		ar, _ := ParseActionRef(uses, Repository{})
+		return serial.Resolve(ctx, ar)
+	}, 2)
+	assert.Equal(t, expected, tree)
+}
+
+func TestPrefetch_BoundedWorkers(t *testing.T) {
+	files := map[string]string{"./action/action.yml": "name: Shared\nruns:\n  using: node20\n  main: index.js\n"}
+	root := "jobs:\n"
+	for i := range 10 {
+		root += fmt.Sprintf("  job%d:\n    uses: ./.github/workflows/wf%d.yml\n", i, i)
+		files[fmt.Sprintf("./.github/workflows/wf%d.yml", i)] = "jobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: ./action\n"
+	}
+	wf, err := ParseWorkflowYAML("root.yml", []byte(root))
+	assert.NoError(t, err)
+
+	// Without a Resolver, every fetch is a download: the workers bound the fetches themselves.
+	client := &slowDownloader{files: files}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		ar, _ := ParseActionRef(uses, Repository{})
+		return FetchActionWorkflow(ctx, client, ar)
+	}
+
+	Prefetch(context.Background(), wf, fetcher, 3, 3)
+	assert.LessOrEqual(t, client.maxInFlight, 3)
+	assert.Equal(t, 10, client.calls["./action/action.yml"])
+	assert.Equal(t, 20, client.total())
+}
+
+func TestResolver_Canceled(t *testing.T) {
+	client := &slowDownloader{files: map[string]string{"./a.yml": "jobs:\n  build:\n    runs-on: ubuntu-latest\n"}}
+	resolver := NewResolver(client, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resolver.sem <- struct{}{} // every worker is busy
+	cancel()
+	wf, err := resolver.Resolve(ctx, ActionRef{Type: "local", Path: "./a.yml"})
+	assert.Nil(t, wf)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, client.total())
+}
@@ -0,0 +1,84 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxStepLabel is the maximum length of the label of a step built from its script.
+const maxStepLabel = 40
+
+// Label returns the name of the step, or its 'uses' reference, or the first line of its script, truncated.
+func (s Step) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	if s.Uses != "" {
+		return s.Uses
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(s.Run), "\n")
+	line = strings.TrimSpace(line)
+	if runes := []rune(line); len(runes) > maxStepLabel {
+		line = strings.TrimSpace(string(runes[:maxStepLabel-3])) + "..."
+	}
+	return line
+}
+
+// WithSteps returns a copy of the tree where the jobs and the expanded composite actions list every one of
+// their steps, in order, instead of the steps that use an action only. Steps are NodeKindStep nodes
+// carrying their definition and the UniqueID of the previous step, identified by their position like the
+// nodes of the steps using an action (see stepID); those keep their children in the original tree.
+func (n *UsesNode) WithSteps() *UsesNode {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	c.Children = nil
+
+	steps := n.steps()
+	byID := make(map[string]*UsesNode, len(n.Children))
+	for _, child := range n.Children {
+		byID[child.UniqueID] = child
+	}
+	previous := ""
+	for i, step := range steps {
+		id := stepID(n.UniqueID, i)
+		s, ok := byID[id]
+		if ok && s.Uses == step.Uses {
+			s = s.WithSteps()
+			delete(byID, id)
+		} else {
+			s = &UsesNode{UniqueID: id, Uses: step.Uses}
+		}
+		s.Name = step.Label()
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("#%d", i+1)
+		}
+		s.Kind, s.Step, s.Previous = NodeKindStep, &step, previous
+		previous = s.UniqueID
+		c.Children = append(c.Children, s)
+	}
+	for _, child := range n.Children {
+		if _, ok := byID[child.UniqueID]; ok {
+			c.Children = append(c.Children, child.WithSteps())
+		}
+	}
+	return &c
+}
+
+// stepID returns the UniqueID of the step at the given index of the steps of a job or action: its position
+// ('#1', '#2', ...) under the job or action, whatever its 'uses' reference.
+func stepID(parentID string, index int) string {
+	return fmt.Sprintf("%s/#%d", parentID, index+1)
+}
+
+// steps returns the steps of a job, or of a composite action expanded under the node.
+func (n *UsesNode) steps() []Step {
+	switch {
+	case n.Kind == NodeKindJob && n.Job != nil && n.Job.Uses == "":
+		return n.Job.Steps
+	case n.Action != nil && n.Action.IsComposite() && len(n.Cycle) == 0:
+		return n.Action.Runs.Steps
+	}
+	return nil
+}
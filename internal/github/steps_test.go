@@ -0,0 +1,107 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStep_Label(t *testing.T) {
+	cases := []struct {
+		step Step
+		want string
+	}{
+		{step: Step{Name: "Build", Run: "make"}, want: "Build"},
+		{step: Step{Uses: "actions/checkout@v4"}, want: "actions/checkout@v4"},
+		{step: Step{Run: "\n  make build\nmake test\n"}, want: "make build"},
+		{step: Step{Run: "go test ./... -race -coverprofile=cover.out -covermode=atomic"}, want: "go test ./... -race -coverprofile=cov..."},
+		{step: Step{}, want: ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.step.Label())
+	}
+}
+
+func TestUsesNode_WithSteps(t *testing.T) {
+	wf := &Workflow{
+		Jobs: map[string]Job{
+			"build": {Steps: []Step{
+				{Run: "make"},
+				{Name: "Setup", ID: "setup", Uses: "./actions/setup"},
+				{Run: "make test", If: "success()"},
+			}},
+		},
+	}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		return &Workflow{URL: "action.yml", Action: &Action{Runs: ActionRuns{Using: "composite", Steps: []Step{
+			{Run: "echo hi", Shell: "bash"},
+		}}}}, nil
+	}
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 2)
+	steps := tree.WithSteps()
+
+	job := steps.Children[0]
+	assert.Len(t, job.Children, 3)
+	run, setup, test := job.Children[0], job.Children[1], job.Children[2]
+
+	assert.Equal(t, NodeKindStep, run.Kind)
+	assert.Equal(t, "make", run.Name)
+	assert.Equal(t, "root/build/#1", run.UniqueID)
+	assert.Empty(t, run.Previous)
+
+	assert.Equal(t, NodeKindStep, setup.Kind)
+	assert.Equal(t, "Setup", setup.Name)
+	assert.Equal(t, "root/build/#2", setup.UniqueID)
+	assert.Equal(t, "setup", setup.Step.ID)
+	assert.Equal(t, run.UniqueID, setup.Previous)
+	assert.Equal(t, "action.yml", setup.URL)
+	assert.Len(t, setup.Children, 1)
+	assert.Equal(t, "echo hi", setup.Children[0].Name)
+	assert.Equal(t, "bash", setup.Children[0].Step.Shell)
+	assert.Equal(t, "root/build/#2/#1", setup.Children[0].UniqueID)
+
+	assert.Equal(t, "root/build/#3", test.UniqueID)
+	assert.Equal(t, setup.UniqueID, test.Previous)
+	assert.Equal(t, "success()", test.Step.If)
+
+	// The original tree is left as is.
+	assert.Len(t, tree.Children[0].Children, 1)
+	assert.Equal(t, NodeKindAction, tree.Children[0].Children[0].Kind)
+	assert.Empty(t, tree.Children[0].Children[0].Children)
+}
+
+func TestUsesNode_WithSteps_ReusableWorkflow(t *testing.T) {
+	wf := &Workflow{Jobs: map[string]Job{"call": {Uses: "./.github/workflows/reusable.yml"}}}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		return &Workflow{URL: "reusable.yml", Jobs: map[string]Job{"a": {Steps: []Step{{Run: "make"}}}}}, nil
+	}
+	steps := BuildUsesTree(context.Background(), "root", wf, fetcher, 2).WithSteps()
+
+	call := steps.Children[0]
+	assert.Equal(t, NodeKindJob, call.Kind)
+	assert.Len(t, call.Children, 1)
+	assert.Equal(t, NodeKindJob, call.Children[0].Kind)
+	assert.Equal(t, "make", call.Children[0].Children[0].Name)
+	assert.Nil(t, (*UsesNode)(nil).WithSteps())
+}
+
+func TestUsesNode_WithSteps_RepeatedUses(t *testing.T) {
+	wf := &Workflow{Jobs: map[string]Job{"build": {Steps: []Step{
+		{Uses: "actions/checkout@v4"},
+		{Run: "echo hi"},
+		{Uses: "actions/checkout@v4"},
+	}}}}
+	tree := BuildUsesTree(context.Background(), "root", wf, nil, 2)
+
+	job := tree.Children[0]
+	assert.Len(t, job.Children, 2)
+	assert.Equal(t, "root/build/#1", job.Children[0].UniqueID)
+	assert.Equal(t, "root/build/#3", job.Children[1].UniqueID)
+	assert.Equal(t, "actions/checkout@v4", job.Children[1].Name)
+
+	steps := tree.WithSteps().Children[0].Children
+	assert.Len(t, steps, 3)
+	assert.Equal(t, []string{"root/build/#1", "root/build/#2", "root/build/#3"}, []string{steps[0].UniqueID, steps[1].UniqueID, steps[2].UniqueID})
+	assert.Equal(t, []string{"", "root/build/#1", "root/build/#2"}, []string{steps[0].Previous, steps[1].Previous, steps[2].Previous})
+}
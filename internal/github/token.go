@@ -0,0 +1,124 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables holding a GitHub token, in order of precedence. On GitHub Enterprise Server
+// hosts only the enterprise variables used by the gh CLI are checked, so a github.com token is never sent
+// to another host.
+var (
+	tokenEnvVars           = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+	enterpriseTokenEnvVars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+)
+
+// ResolveToken returns the GitHub token to use for host and a description of where it was found.
+// The token is taken, in order, from flagToken, from tokenFile, from the environment ($GITHUB_TOKEN,
+// $GH_TOKEN, or $GH_ENTERPRISE_TOKEN, $GITHUB_ENTERPRISE_TOKEN on GitHub Enterprise Server), then from the
+// hosts.yml file of the gh CLI. A hosts.yml file that cannot be read is reported as a warning and skipped.
+// An empty token (and source) means anonymous access.
+func ResolveToken(flagToken, tokenFile, host string) (token, source string, err error) {
+	if flagToken != "" {
+		return flagToken, "--token flag", nil
+	}
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", "", fmt.Errorf("token file %s is empty", tokenFile)
+		}
+		return token, "token file " + tokenFile, nil
+	}
+
+	envVars := tokenEnvVars
+	if IsEnterpriseHost(host) {
+		envVars = enterpriseTokenEnvVars
+	}
+	for _, name := range envVars {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token, "$" + name, nil
+		}
+	}
+
+	path, err := ghHostsFile()
+	if err != nil {
+		return "", "", nil
+	}
+	token, err = ghConfigToken(path, host)
+	if err != nil {
+		slog.Warn("Ignoring the gh CLI configuration", "error", err)
+		return "", "", nil
+	}
+	if token != "" {
+		return token, "gh config " + path, nil
+	}
+	return "", "", nil
+}
+
+// ghHostsFile returns the path of the hosts.yml file of the gh CLI, honouring $GH_CONFIG_DIR and
+// $XDG_CONFIG_HOME like gh does.
+func ghHostsFile() (string, error) {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml"), nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml"), nil
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI", "hosts.yml"), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml"), nil
+}
+
+// ghHost is the entry of a host in the hosts.yml file of the gh CLI.
+type ghHost struct {
+	User       string `yaml:"user"`
+	OAuthToken string `yaml:"oauth_token"`
+	Users      map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	} `yaml:"users"`
+}
+
+// ghConfigToken returns the token stored for host in the given gh hosts.yml file, or an empty string
+// when the file does not exist or holds no token for host (e.g. gh keeps it in the system keyring).
+func ghConfigToken(path, host string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read gh config: %w", err)
+	}
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return "", fmt.Errorf("failed to parse gh config %s: %w", path, err)
+	}
+	host = NormalizeHost(host)
+	for name, entry := range hosts {
+		if NormalizeHost(name) != host {
+			continue
+		}
+		if entry.OAuthToken != "" {
+			return entry.OAuthToken, nil
+		}
+		return entry.Users[entry.User].OAuthToken, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,117 @@
+package github
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// isolateTokenEnv clears the token environment variables and points the gh config at an empty directory.
+func isolateTokenEnv(t *testing.T) string {
+	for _, name := range append(tokenEnvVars, enterpriseTokenEnvVars...) {
+		t.Setenv(name, "")
+	}
+	dir := t.TempDir()
+	t.Setenv("GH_CONFIG_DIR", dir)
+	return dir
+}
+
+func TestResolveToken_Precedence(t *testing.T) {
+	dir := isolateTokenEnv(t)
+	hosts := `github.com:
+    user: octocat
+    oauth_token: gho_config
+ghes.example.com:
+    user: admin
+    users:
+        admin:
+            oauth_token: gho_enterprise
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "hosts.yml"), []byte(hosts), 0o600))
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("file_token\n"), 0o600))
+
+	token, source, err := ResolveToken("", "", DefaultHost)
+	assert.NoError(t, err)
+	assert.Equal(t, "gho_config", token)
+	assert.Equal(t, "gh config "+filepath.Join(dir, "hosts.yml"), source)
+
+	token, _, err = ResolveToken("", "", "https://GHES.example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, "gho_enterprise", token)
+
+	t.Setenv("GH_TOKEN", "gh_env")
+	token, source, _ = ResolveToken("", "", DefaultHost)
+	assert.Equal(t, "gh_env", token)
+	assert.Equal(t, "$GH_TOKEN", source)
+
+	t.Setenv("GITHUB_TOKEN", "github_env")
+	token, source, _ = ResolveToken("", "", DefaultHost)
+	assert.Equal(t, "github_env", token)
+	assert.Equal(t, "$GITHUB_TOKEN", source)
+	token, _, _ = ResolveToken("", "", "ghes.example.com")
+	assert.Equal(t, "gho_enterprise", token, "github.com variables do not apply to GHES hosts")
+
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise_env")
+	token, _, _ = ResolveToken("", "", DefaultHost)
+	assert.Equal(t, "github_env", token, "enterprise variables only apply to GHES hosts")
+	token, source, _ = ResolveToken("", "", "ghes.example.com")
+	assert.Equal(t, "enterprise_env", token)
+	assert.Equal(t, "$GH_ENTERPRISE_TOKEN", source)
+
+	token, source, _ = ResolveToken("", tokenFile, DefaultHost)
+	assert.Equal(t, "file_token", token)
+	assert.Equal(t, "token file "+tokenFile, source)
+
+	token, source, _ = ResolveToken("flag_token", tokenFile, DefaultHost)
+	assert.Equal(t, "flag_token", token)
+	assert.Equal(t, "--token flag", source)
+}
+
+func TestResolveToken_None(t *testing.T) {
+	isolateTokenEnv(t)
+	token, source, err := ResolveToken("", "", DefaultHost)
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+	assert.Empty(t, source)
+}
+
+func TestResolveToken_Errors(t *testing.T) {
+	dir := isolateTokenEnv(t)
+
+	_, _, err := ResolveToken("", filepath.Join(dir, "missing"), DefaultHost)
+	assert.ErrorContains(t, err, "failed to read token file")
+
+	empty := filepath.Join(dir, "empty")
+	assert.NoError(t, os.WriteFile(empty, []byte(" \n"), 0o600))
+	_, _, err = ResolveToken("", empty, DefaultHost)
+	assert.ErrorContains(t, err, "is empty")
+
+}
+
+func TestResolveToken_MalformedGHConfig(t *testing.T) {
+	dir := isolateTokenEnv(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "hosts.yml"), []byte("github.com: [unclosed"), 0o600))
+
+	token, source, err := ResolveToken("", "", DefaultHost)
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+	assert.Empty(t, source)
+
+	_, err = ghConfigToken(filepath.Join(dir, "hosts.yml"), DefaultHost)
+	assert.ErrorContains(t, err, "failed to parse gh config")
+}
+
+func TestGHHostsFile(t *testing.T) {
+	t.Setenv("GH_CONFIG_DIR", "")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	path, err := ghHostsFile()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("/xdg", "gh", "hosts.yml"), path)
+
+	t.Setenv("GH_CONFIG_DIR", "/gh")
+	path, _ = ghHostsFile()
+	assert.Equal(t, filepath.Join("/gh", "hosts.yml"), path)
+}
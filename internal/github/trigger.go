@@ -0,0 +1,185 @@
+package github
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Triggers holds the events declared under 'on:' in YAML document order.
+type Triggers []Trigger
+
+// Trigger represents a single event that starts a workflow.
+type Trigger struct {
+	Event          string
+	Types          []string // activity types (pull_request, repository_dispatch, ...)
+	Branches       []string
+	BranchesIgnore []string
+	Tags           []string
+	TagsIgnore     []string
+	Paths          []string
+	PathsIgnore    []string
+	Workflows      []string // workflow_run source workflows
+	Crons          []string // schedule cron expressions
+	Inputs         map[string]TriggerInput
+	Secrets        map[string]TriggerSecret
+	Outputs        map[string]TriggerOutput
+}
+
+// TriggerInput represents an input of a workflow_dispatch or workflow_call trigger.
+type TriggerInput struct {
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Default     string   `yaml:"default"`
+	Type        string   `yaml:"type"`
+	Options     []string `yaml:"options"`
+}
+
+// TriggerSecret represents a secret declared by a workflow_call trigger.
+type TriggerSecret struct {
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+}
+
+// TriggerOutput represents an output declared by a workflow_call trigger.
+type TriggerOutput struct {
+	Description string `yaml:"description"`
+	Value       string `yaml:"value"`
+}
+
+// StringList handles both string and []string values.
+type StringList []string
+
+// triggerConfig is the mapping form of a trigger.
+type triggerConfig struct {
+	Types          StringList               `yaml:"types"`
+	Branches       StringList               `yaml:"branches"`
+	BranchesIgnore StringList               `yaml:"branches-ignore"`
+	Tags           StringList               `yaml:"tags"`
+	TagsIgnore     StringList               `yaml:"tags-ignore"`
+	Paths          StringList               `yaml:"paths"`
+	PathsIgnore    StringList               `yaml:"paths-ignore"`
+	Workflows      StringList               `yaml:"workflows"`
+	Inputs         map[string]TriggerInput  `yaml:"inputs"`
+	Secrets        map[string]TriggerSecret `yaml:"secrets"`
+	Outputs        map[string]TriggerOutput `yaml:"outputs"`
+}
+
+// UnmarshalYAML custom unmarshal for Triggers to support the string, list and map forms of 'on:'.
+func (t *Triggers) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*t = Triggers{{Event: value.Value}}
+		return nil
+	case yaml.SequenceNode:
+		var events []string
+		if err := value.Decode(&events); err != nil {
+			return fmt.Errorf("invalid on field: %w", err)
+		}
+		triggers := make(Triggers, 0, len(events))
+		for _, event := range events {
+			triggers = append(triggers, Trigger{Event: event})
+		}
+		*t = triggers
+		return nil
+	case yaml.MappingNode:
+		triggers := make(Triggers, 0, len(value.Content)/2)
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			trigger, err := parseTrigger(value.Content[i].Value, value.Content[i+1])
+			if err != nil {
+				return err
+			}
+			triggers = append(triggers, trigger)
+		}
+		*t = triggers
+		return nil
+	}
+	return fmt.Errorf("invalid on field: %v", value.Value)
+}
+
+// parseTrigger decodes the configuration of a single event.
+func parseTrigger(event string, value *yaml.Node) (Trigger, error) {
+	trigger := Trigger{Event: event}
+	switch {
+	case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+		return trigger, nil
+	case event == "schedule":
+		var schedules []struct {
+			Cron string `yaml:"cron"`
+		}
+		if err := value.Decode(&schedules); err != nil {
+			return trigger, fmt.Errorf("invalid schedule trigger: %w", err)
+		}
+		for _, s := range schedules {
+			trigger.Crons = append(trigger.Crons, s.Cron)
+		}
+		return trigger, nil
+	case value.Kind == yaml.MappingNode:
+		var cfg triggerConfig
+		if err := value.Decode(&cfg); err != nil {
+			return trigger, fmt.Errorf("invalid %s trigger: %w", event, err)
+		}
+		trigger.Types = cfg.Types
+		trigger.Branches = cfg.Branches
+		trigger.BranchesIgnore = cfg.BranchesIgnore
+		trigger.Tags = cfg.Tags
+		trigger.TagsIgnore = cfg.TagsIgnore
+		trigger.Paths = cfg.Paths
+		trigger.PathsIgnore = cfg.PathsIgnore
+		trigger.Workflows = cfg.Workflows
+		trigger.Inputs = cfg.Inputs
+		trigger.Secrets = cfg.Secrets
+		trigger.Outputs = cfg.Outputs
+		return trigger, nil
+	}
+	return trigger, fmt.Errorf("invalid %s trigger: %v", event, value.Value)
+}
+
+// Details returns a human readable line for each filter or parameter of the trigger, in a stable order.
+func (t Trigger) Details() []string {
+	var details []string
+	add := func(label string, values []string) {
+		if len(values) > 0 {
+			details = append(details, label+": "+strings.Join(values, ", "))
+		}
+	}
+	add("types", t.Types)
+	add("branches", t.Branches)
+	add("branches-ignore", t.BranchesIgnore)
+	add("tags", t.Tags)
+	add("tags-ignore", t.TagsIgnore)
+	add("paths", t.Paths)
+	add("paths-ignore", t.PathsIgnore)
+	add("workflows", t.Workflows)
+	add("cron", t.Crons)
+	add("inputs", sortedKeys(t.Inputs))
+	add("secrets", sortedKeys(t.Secrets))
+	add("outputs", sortedKeys(t.Outputs))
+	return details
+}
+
+// sortedKeys returns the keys of the map in alphabetical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// UnmarshalYAML custom unmarshal for StringList to support string or []string.
+func (s *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = StringList{value.Value}
+		return nil
+	}
+	var multi []string
+	if err := value.Decode(&multi); err != nil {
+		return fmt.Errorf("invalid list field: %w", err)
+	}
+	*s = StringList(multi)
+	return nil
+}
@@ -0,0 +1,97 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkflowYAML_OnString(t *testing.T) {
+	wf, err := ParseWorkflowYAML("", []byte("on: push\njobs: {}\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, Triggers{{Event: "push"}}, wf.On)
+}
+
+func TestParseWorkflowYAML_OnList(t *testing.T) {
+	wf, err := ParseWorkflowYAML("", []byte("on: [push, pull_request]\njobs: {}\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, Triggers{{Event: "push"}, {Event: "pull_request"}}, wf.On)
+}
+
+func TestParseWorkflowYAML_OnMap(t *testing.T) {
+	yamlData := []byte(`
+on:
+  push:
+    branches: [main, 'release/**']
+    paths-ignore: docs/**
+  pull_request:
+    types: [opened, synchronize]
+  schedule:
+    - cron: '0 3 * * 1'
+    - cron: '0 4 * * 5'
+  workflow_dispatch:
+    inputs:
+      environment:
+        description: Target environment
+        type: choice
+        options: [staging, production]
+        required: true
+  workflow_call:
+    inputs:
+      version:
+        type: string
+    secrets:
+      token:
+        required: true
+    outputs:
+      digest:
+        value: ${{ jobs.build.outputs.digest }}
+  repository_dispatch:
+    types: deploy
+  release:
+jobs: {}
+`)
+	wf, err := ParseWorkflowYAML("", yamlData)
+	assert.NoError(t, err)
+	assert.Len(t, wf.On, 7)
+
+	var events []string
+	for _, trigger := range wf.On {
+		events = append(events, trigger.Event)
+	}
+	assert.Equal(t, []string{"push", "pull_request", "schedule", "workflow_dispatch", "workflow_call", "repository_dispatch", "release"}, events)
+
+	push := wf.On[0]
+	assert.Equal(t, []string{"main", "release/**"}, push.Branches)
+	assert.Equal(t, []string{"docs/**"}, push.PathsIgnore)
+	assert.Equal(t, []string{"branches: main, release/**", "paths-ignore: docs/**"}, push.Details())
+
+	assert.Equal(t, []string{"opened", "synchronize"}, wf.On[1].Types)
+	assert.Equal(t, []string{"0 3 * * 1", "0 4 * * 5"}, wf.On[2].Crons)
+
+	dispatch := wf.On[3]
+	assert.True(t, dispatch.Inputs["environment"].Required)
+	assert.Equal(t, []string{"staging", "production"}, dispatch.Inputs["environment"].Options)
+
+	call := wf.On[4]
+	assert.Equal(t, "string", call.Inputs["version"].Type)
+	assert.True(t, call.Secrets["token"].Required)
+	assert.Equal(t, "${{ jobs.build.outputs.digest }}", call.Outputs["digest"].Value)
+	assert.Equal(t, []string{"inputs: version", "secrets: token", "outputs: digest"}, call.Details())
+
+	assert.Equal(t, []string{"deploy"}, wf.On[5].Types)
+	assert.Empty(t, wf.On[6].Details())
+}
+
+func TestParseWorkflowYAML_OnInvalid(t *testing.T) {
+	_, err := ParseWorkflowYAML("", []byte("on:\n  push: main\njobs: {}\n"))
+	assert.Error(t, err)
+}
+
+func TestBuildUsesTree_Triggers(t *testing.T) {
+	wf := &Workflow{On: Triggers{{Event: "push"}}, Jobs: map[string]Job{"a": {}}}
+	tree := BuildUsesTree(context.Background(), "root", wf, nil, 2)
+	assert.Equal(t, Triggers{{Event: "push"}}, tree.Triggers)
+	assert.Empty(t, tree.Children[0].Triggers)
+}
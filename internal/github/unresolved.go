@@ -0,0 +1,91 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+)
+
+// Reasons why a 'uses' reference could not be resolved.
+const (
+	UnresolvedNotFound   = "not found"
+	UnresolvedAuth       = "unauthorized"
+	UnresolvedRateLimit  = "rate limited"
+	UnresolvedParse      = "parse error"
+	UnresolvedInvalid    = "invalid reference"
+	UnresolvedDepthLimit = "depth limit"
+	UnresolvedError      = "error"
+)
+
+// ResolveError is returned when a 'uses' reference cannot be resolved to a workflow or action.
+type ResolveError struct {
+	Uses   string
+	Reason string // one of the Unresolved constants
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *ResolveError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("failed to resolve %s: %s", e.Uses, e.Reason)
+	}
+	return fmt.Sprintf("failed to resolve %s: %s: %v", e.Uses, e.Reason, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// newResolveError wraps err into a *ResolveError for uses, classifying its reason.
+func newResolveError(uses string, err error) *ResolveError {
+	var resolveErr *ResolveError
+	if errors.As(err, &resolveErr) {
+		return resolveErr
+	}
+	return &ResolveError{Uses: uses, Reason: unresolvedReason(err), Err: err}
+}
+
+// unresolvedReason classifies a download error.
+func unresolvedReason(err error) string {
+	var statusErr *StatusError
+	var rateLimitErr *RateLimitError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		return UnresolvedRateLimit
+	case isNotFound(err), errors.Is(err, fs.ErrNotExist):
+		return UnresolvedNotFound
+	case errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden):
+		return UnresolvedAuth
+	default:
+		return UnresolvedError
+	}
+}
+
+// Problem is a reference of the tree that could not be resolved.
+type Problem struct {
+	NodeID string
+	Uses   string
+	Reason string
+	Err    error
+}
+
+// Problems returns the unresolved references of the tree, in depth first order. References that were
+// only left unexpanded because of the depth limit are not problems and are not returned.
+func Problems(root *UsesNode) []Problem {
+	var problems []Problem
+	var visit func(n *UsesNode)
+	visit = func(n *UsesNode) {
+		if n.Unresolved != nil && n.Unresolved.Reason != UnresolvedDepthLimit {
+			problems = append(problems, Problem{NodeID: n.UniqueID, Uses: n.Uses, Reason: n.Unresolved.Reason, Err: n.Unresolved.Err})
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	if root != nil {
+		visit(root)
+	}
+	return problems
+}
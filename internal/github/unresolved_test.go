@@ -0,0 +1,77 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnresolvedReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&StatusError{StatusCode: http.StatusNotFound}, UnresolvedNotFound},
+		{fmt.Errorf("failed to read: %w", fs.ErrNotExist), UnresolvedNotFound},
+		{&StatusError{StatusCode: http.StatusUnauthorized}, UnresolvedAuth},
+		{&StatusError{StatusCode: http.StatusForbidden}, UnresolvedAuth},
+		{&RateLimitError{StatusCode: http.StatusTooManyRequests}, UnresolvedRateLimit},
+		{&StatusError{StatusCode: http.StatusBadGateway}, UnresolvedError},
+		{errors.New("boom"), UnresolvedError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, unresolvedReason(tt.err), tt.err.Error())
+	}
+}
+
+func TestNewResolveError(t *testing.T) {
+	err := newResolveError("./a", &StatusError{StatusCode: http.StatusNotFound})
+	assert.Equal(t, UnresolvedNotFound, err.Reason)
+	assert.Equal(t, "./a", err.Uses)
+
+	wrapped := fmt.Errorf("resolver: %w", &ResolveError{Uses: "./b", Reason: UnresolvedParse})
+	assert.Equal(t, &ResolveError{Uses: "./b", Reason: UnresolvedParse}, newResolveError("./a", wrapped))
+}
+
+func TestProblems(t *testing.T) {
+	notFound := &ResolveError{Uses: "./missing.yml", Reason: UnresolvedNotFound}
+	root := &UsesNode{UniqueID: "root", Children: []*UsesNode{
+		{UniqueID: "root/a", Uses: "./missing.yml", Unresolved: notFound},
+		{UniqueID: "root/b", Uses: "./deep.yml", Unresolved: &ResolveError{Reason: UnresolvedDepthLimit}},
+		{UniqueID: "root/c", Children: []*UsesNode{
+			{UniqueID: "root/c/x", Uses: "x", Unresolved: &ResolveError{Uses: "x", Reason: UnresolvedInvalid}},
+		}},
+	}}
+
+	assert.Equal(t, []Problem{
+		{NodeID: "root/a", Uses: "./missing.yml", Reason: UnresolvedNotFound},
+		{NodeID: "root/c/x", Uses: "x", Reason: UnresolvedInvalid},
+	}, Problems(root))
+	assert.Empty(t, Problems(nil))
+}
+
+func TestBuildUsesTree_Unresolved(t *testing.T) {
+	wf := &Workflow{
+		Jobs: map[string]Job{
+			"call":  {Uses: "./missing.yml"},
+			"build": {Steps: []Step{{Uses: "./actions/composite"}}},
+		},
+	}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		if uses == "./actions/composite" {
+			return &Workflow{Action: &Action{Runs: ActionRuns{Using: "composite", Steps: []Step{{Uses: "./actions/nested"}}}}}, nil
+		}
+		return nil, &StatusError{StatusCode: http.StatusNotFound}
+	}
+
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 2)
+	build, call := tree.Children[0], tree.Children[1]
+	assert.Equal(t, UnresolvedNotFound, call.Unresolved.Reason)
+	assert.Nil(t, build.Children[0].Unresolved)
+	assert.Equal(t, UnresolvedDepthLimit, build.Children[0].Children[0].Unresolved.Reason)
+}
@@ -0,0 +1,206 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Types of 'uses' references: where the referenced action or workflow lives.
+const (
+	UsesTypeLocal  = "local"  // ./path in the repository of the workflow
+	UsesTypeRemote = "remote" // owner/repo[/path]@ref
+	UsesTypeDocker = "docker" // docker://image
+)
+
+// Targets of 'uses' references: what is referenced.
+const (
+	UsesTargetAction   = "action"
+	UsesTargetWorkflow = "workflow"
+)
+
+// ErrInvalidUses is wrapped by the errors of ParseActionRef.
+var ErrInvalidUses = errors.New("invalid 'uses' reference")
+
+var (
+	// usesNamePattern matches an owner or repository name.
+	usesNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	// dockerImagePattern matches [registry[:port]/]name[/name...][:tag][@digest].
+	dockerImagePattern = regexp.MustCompile(`^(?:[A-Za-z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:[._-]+[a-z0-9]+)*(?:/[a-z0-9]+(?:[._-]+[a-z0-9]+)*)*(?::\w[\w.-]{0,127})?(?:@[a-z0-9]+:[a-f0-9]{32,})?$`)
+)
+
+// ParseActionRef parses a 'uses' string and returns an ActionRef, classifying it as a local action
+// (./path/to/dir), a local reusable workflow (./.github/workflows/file.yml), a remote action
+// (owner/repo[/path]@ref), a remote reusable workflow (owner/repo/.github/workflows/file.yml@ref)
+// or a docker image (docker://image[:tag]). Refs that are commit SHAs are recorded as such.
+// Local references are bound to repo, the repository of the workflow they appear in.
+// Returns an error wrapping ErrInvalidUses telling what is wrong with invalid references.
+func ParseActionRef(uses string, repo Repository) (ActionRef, error) {
+	ar := ActionRef{Raw: uses}
+
+	var err error
+	switch {
+	case uses == "":
+		err = invalidUses(uses, "empty reference")
+	case strings.TrimSpace(uses) != uses || strings.ContainsAny(uses, " \t\n"):
+		err = invalidUses(uses, "whitespace is not allowed")
+	case strings.Contains(uses, "${{"):
+		err = invalidUses(uses, "expressions are not allowed")
+	case strings.HasPrefix(uses, "docker://"):
+		ar, err = parseDockerRef(ar)
+	case strings.HasPrefix(uses, "./"):
+		ar, err = parseLocalRef(ar, repo)
+	case strings.HasPrefix(uses, "/") || strings.HasPrefix(uses, "../") || strings.HasPrefix(uses, ".github/"):
+		err = invalidUses(uses, "local references must start with ./")
+	case strings.Contains(uses, "://"):
+		err = invalidUses(uses, "only the docker:// scheme is supported")
+	default:
+		ar, err = parseRemoteRef(ar)
+	}
+	if err != nil {
+		return ActionRef{Raw: uses}, err
+	}
+	return ar, nil
+}
+
+// parseDockerRef parses a docker://image reference.
+func parseDockerRef(ar ActionRef) (ActionRef, error) {
+	image := strings.TrimPrefix(ar.Raw, "docker://")
+	if image == "" {
+		return ar, invalidUses(ar.Raw, "missing docker image")
+	}
+	if !dockerImagePattern.MatchString(image) {
+		return ar, invalidUses(ar.Raw, "malformed docker image %q", image)
+	}
+	ar.Type = UsesTypeDocker
+	ar.Target = UsesTargetAction
+	ar.Path = image
+
+	slog.Debug("Identified a docker image", "uses", ar.Raw, "image", image)
+
+	return ar, nil
+}
+
+// parseLocalRef parses a ./path reference to an action directory or a reusable workflow of repo.
+func parseLocalRef(ar ActionRef, repo Repository) (ActionRef, error) {
+	if strings.Contains(ar.Raw, "@") {
+		return ar, invalidUses(ar.Raw, "local references cannot have a @ref, they always use the commit of the workflow")
+	}
+	if repo.IsRemote() {
+		ar.Owner, ar.Repo, ar.Ref = repo.Owner, repo.Name, repo.Ref
+	} else {
+		ar.Dir = repo.Dir
+	}
+	p := strings.TrimSuffix(ar.Raw, "/")
+	if p == "." {
+		ar.Type = UsesTypeLocal
+		ar.Target = UsesTargetAction
+		ar.Path = "./"
+		return ar, nil
+	}
+	if err := checkPath(ar.Raw, strings.TrimPrefix(p, "./")); err != nil {
+		return ar, err
+	}
+	ar.Type = UsesTypeLocal
+	ar.Path = ar.Raw
+	ar.Target = UsesTargetAction
+	if isWorkflowFile(p) {
+		if path.Dir(strings.TrimPrefix(p, "./")) != ".github/workflows" {
+			return ar, invalidUses(ar.Raw, "reusable workflows must be located in .github/workflows")
+		}
+		ar.Target = UsesTargetWorkflow
+	}
+
+	slog.Debug("Identified a local "+ar.Target, "uses", ar.Raw, "path", ar.Path, "dir", ar.Dir, "owner", ar.Owner, "repo", ar.Repo, "ref", ar.Ref)
+
+	return ar, nil
+}
+
+// parseRemoteRef parses an owner/repo[/path]@ref reference.
+func parseRemoteRef(ar ActionRef) (ActionRef, error) {
+	location, ref, found := strings.Cut(ar.Raw, "@")
+	if !found || ref == "" {
+		return ar, invalidUses(ar.Raw, "missing @ref (a tag, branch or commit SHA)")
+	}
+	if strings.ContainsAny(ref, "~^:?*[\\") || strings.Contains(ref, "..") || strings.Contains(ref, "@") {
+		return ar, invalidUses(ar.Raw, "malformed ref %q", ref)
+	}
+	parts := strings.SplitN(location, "/", 3)
+	if len(parts) < 2 {
+		return ar, invalidUses(ar.Raw, "expected owner/repo[/path]@ref")
+	}
+	if !usesNamePattern.MatchString(parts[0]) {
+		return ar, invalidUses(ar.Raw, "malformed owner %q", parts[0])
+	}
+	if !usesNamePattern.MatchString(parts[1]) {
+		return ar, invalidUses(ar.Raw, "malformed repository %q", parts[1])
+	}
+	ar.Type = UsesTypeRemote
+	ar.Owner = parts[0]
+	ar.Repo = parts[1]
+	ar.Ref = ref
+	ar.Target = UsesTargetAction
+	if len(parts) == 3 {
+		p := strings.TrimSuffix(parts[2], "/")
+		if err := checkPath(ar.Raw, p); err != nil {
+			return ar, err
+		}
+		ar.Path = p
+		if isWorkflowFile(p) {
+			if path.Dir(p) != ".github/workflows" {
+				return ar, invalidUses(ar.Raw, "reusable workflows must be located in .github/workflows")
+			}
+			ar.Target = UsesTargetWorkflow
+		}
+	}
+	if isCommitSHA(ref) {
+		ar.SHA = ref
+		ar.RefKind = RefKindCommit
+	}
+
+	slog.Debug("Identified a remote "+ar.Target, "uses", ar.Raw, "owner", ar.Owner, "repo", ar.Repo, "ref", ar.Ref, "path", ar.Path)
+
+	return ar, nil
+}
+
+// checkPath returns an error if p, the path of a reference relative to its repository, is not a clean path.
+func checkPath(uses, p string) error {
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "":
+			return invalidUses(uses, "empty path segment")
+		case ".", "..":
+			return invalidUses(uses, "path cannot contain %q segments", segment)
+		}
+	}
+	return nil
+}
+
+// invalidUses returns an error wrapping ErrInvalidUses for uses.
+func invalidUses(uses, format string, args ...any) error {
+	return fmt.Errorf("%w %q: %s", ErrInvalidUses, uses, fmt.Sprintf(format, args...))
+}
+
+// IsWorkflow reports whether the reference points at a reusable workflow rather than an action.
+// References built without a target are classified by the extension of their path.
+func (ar ActionRef) IsWorkflow() bool {
+	if ar.Target == "" {
+		return isWorkflowFile(ar.Path)
+	}
+	return ar.Target == UsesTargetWorkflow
+}
+
+// Class returns a human readable classification of the reference, e.g. "remote reusable workflow".
+func (ar ActionRef) Class() string {
+	switch {
+	case ar.Type == UsesTypeDocker:
+		return "docker image"
+	case ar.IsWorkflow():
+		return ar.Type + " reusable workflow"
+	default:
+		return ar.Type + " action"
+	}
+}
@@ -0,0 +1,140 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sha = "8f4b7f84864484a7bf31766abe9204da3cbe65b3"
+
+func TestParseActionRef(t *testing.T) {
+	tests := []struct {
+		uses  string
+		want  ActionRef
+		class string
+	}{
+		// Local actions and reusable workflows.
+		{"./", ActionRef{Type: UsesTypeLocal, Target: UsesTargetAction, Path: "./"}, "local action"},
+		{"./actions/setup", ActionRef{Type: UsesTypeLocal, Target: UsesTargetAction, Path: "./actions/setup"}, "local action"},
+		{"./actions/setup/", ActionRef{Type: UsesTypeLocal, Target: UsesTargetAction, Path: "./actions/setup/"}, "local action"},
+		{"./.github/actions/lint", ActionRef{Type: UsesTypeLocal, Target: UsesTargetAction, Path: "./.github/actions/lint"}, "local action"},
+		{"./.github/workflows/build.yml", ActionRef{Type: UsesTypeLocal, Target: UsesTargetWorkflow, Path: "./.github/workflows/build.yml"}, "local reusable workflow"},
+		{"./.github/workflows/build.yaml", ActionRef{Type: UsesTypeLocal, Target: UsesTargetWorkflow, Path: "./.github/workflows/build.yaml"}, "local reusable workflow"},
+
+		// Remote actions.
+		{"actions/checkout@v4", ActionRef{Type: UsesTypeRemote, Target: UsesTargetAction, Owner: "actions", Repo: "checkout", Ref: "v4"}, "remote action"},
+		{"actions/checkout@v4.1.7", ActionRef{Type: UsesTypeRemote, Target: UsesTargetAction, Owner: "actions", Repo: "checkout", Ref: "v4.1.7"}, "remote action"},
+		{"actions/checkout@main", ActionRef{Type: UsesTypeRemote, Target: UsesTargetAction, Owner: "actions", Repo: "checkout", Ref: "main"}, "remote action"},
+		{"actions/checkout@" + sha, ActionRef{Type: UsesTypeRemote, Target: UsesTargetAction, Owner: "actions", Repo: "checkout", Ref: sha, SHA: sha, RefKind: RefKindCommit}, "remote action"},
+		{"github/codeql-action/init@v3", ActionRef{Type: UsesTypeRemote, Target: UsesTargetAction, Owner: "github", Repo: "codeql-action", Ref: "v3", Path: "init"}, "remote action"},
+		{"octo-org/my.repo/some/dir@feature/x", ActionRef{Type: UsesTypeRemote, Target: UsesTargetAction, Owner: "octo-org", Repo: "my.repo", Ref: "feature/x", Path: "some/dir"}, "remote action"},
+		{"owner/repo/path/@v1", ActionRef{Type: UsesTypeRemote, Target: UsesTargetAction, Owner: "owner", Repo: "repo", Ref: "v1", Path: "path"}, "remote action"},
+
+		// Remote reusable workflows.
+		{"owner/repo/.github/workflows/build.yml@v1", ActionRef{Type: UsesTypeRemote, Target: UsesTargetWorkflow, Owner: "owner", Repo: "repo", Ref: "v1", Path: ".github/workflows/build.yml"}, "remote reusable workflow"},
+		{"owner/repo/.github/workflows/build.yaml@" + sha, ActionRef{Type: UsesTypeRemote, Target: UsesTargetWorkflow, Owner: "owner", Repo: "repo", Ref: sha, Path: ".github/workflows/build.yaml", SHA: sha, RefKind: RefKindCommit}, "remote reusable workflow"},
+
+		// Docker images.
+		{"docker://alpine", ActionRef{Type: UsesTypeDocker, Target: UsesTargetAction, Path: "alpine"}, "docker image"},
+		{"docker://alpine:3.20", ActionRef{Type: UsesTypeDocker, Target: UsesTargetAction, Path: "alpine:3.20"}, "docker image"},
+		{"docker://ghcr.io/owner/image:latest", ActionRef{Type: UsesTypeDocker, Target: UsesTargetAction, Path: "ghcr.io/owner/image:latest"}, "docker image"},
+		{"docker://localhost:5000/tools/lint", ActionRef{Type: UsesTypeDocker, Target: UsesTargetAction, Path: "localhost:5000/tools/lint"}, "docker image"},
+		{"docker://debian@sha256:" + sha + sha[:24], ActionRef{Type: UsesTypeDocker, Target: UsesTargetAction, Path: "debian@sha256:" + sha + sha[:24]}, "docker image"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			ar, err := ParseActionRef(tt.uses, Repository{})
+			assert.NoError(t, err)
+			tt.want.Raw = tt.uses
+			assert.Equal(t, tt.want, ar)
+			assert.Equal(t, tt.class, ar.Class())
+		})
+	}
+}
+
+func TestParseActionRef_Invalid(t *testing.T) {
+	tests := []struct {
+		uses string
+		err  string
+	}{
+		{"", "empty reference"},
+		{"echo hello", "whitespace is not allowed"},
+		{" actions/checkout@v4", "whitespace is not allowed"},
+		{"actions/checkout@${{ inputs.version }}", "whitespace is not allowed"},
+		{"${{inputs.action}}", "expressions are not allowed"},
+		{"actions/checkout", "missing @ref"},
+		{"actions/checkout@", "missing @ref"},
+		{"owner/repo/.github/workflows/build.yml", "missing @ref"},
+		{"checkout@v4", "expected owner/repo[/path]@ref"},
+		{"-owner/repo@v1", `malformed owner "-owner"`},
+		{"owner/re po@v1", "whitespace is not allowed"},
+		{"owner/@v1", `malformed repository ""`},
+		{"owner/repo@v1..v2", `malformed ref "v1..v2"`},
+		{"owner/repo@v1@v2", `malformed ref "v1@v2"`},
+		{"owner/repo@refs~1", `malformed ref "refs~1"`},
+		{"owner/repo//path@v1", "empty path segment"},
+		{"owner/repo/../other@v1", `path cannot contain ".." segments`},
+		{"owner/repo/workflows/build.yml@v1", "reusable workflows must be located in .github/workflows"},
+		{"owner/repo/.github/workflows/sub/build.yml@v1", "reusable workflows must be located in .github/workflows"},
+		{"./actions/setup@v1", "local references cannot have a @ref"},
+		{"./build.yml", "reusable workflows must be located in .github/workflows"},
+		{"./actions/../setup", `path cannot contain ".." segments`},
+		{".//actions", "empty path segment"},
+		{".github/workflows/build.yml", "local references must start with ./"},
+		{"../actions/setup", "local references must start with ./"},
+		{"/actions/setup", "local references must start with ./"},
+		{"https://github.com/actions/checkout@v4", "only the docker:// scheme is supported"},
+		{"docker://", "missing docker image"},
+		{"docker://Alpine", `malformed docker image "Alpine"`},
+		{"docker://alpine:", `malformed docker image "alpine:"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			ar, err := ParseActionRef(tt.uses, Repository{})
+			assert.ErrorIs(t, err, ErrInvalidUses)
+			assert.ErrorContains(t, err, tt.err)
+			assert.Empty(t, ar.Type)
+		})
+	}
+}
+
+func TestActionRef_IsWorkflow(t *testing.T) {
+	assert.True(t, ActionRef{Path: "./reusable.yml"}.IsWorkflow(), "references without a target are classified by extension")
+	assert.False(t, ActionRef{Path: "./actions/setup"}.IsWorkflow())
+	assert.False(t, ActionRef{Target: UsesTargetAction, Path: "./actions/setup.yml"}.IsWorkflow())
+}
+
+func TestFetchActionWorkflow_Docker(t *testing.T) {
+	client := &mockClient{DownloadWorkflowFunc: func(url string) ([]byte, error) {
+		t.Errorf("Unexpected download of %s", url)
+		return nil, assert.AnError
+	}}
+	ar, err := ParseActionRef("docker://alpine:3.20", Repository{})
+	assert.NoError(t, err)
+	wf, err := FetchActionWorkflow(context.Background(), client, ar)
+	assert.NoError(t, err)
+	assert.Nil(t, wf)
+}
+
+func TestFetchActionWorkflow_RepositoryRootAction(t *testing.T) {
+	var requested []string
+	client := &mockClient{DownloadWorkflowFunc: func(url string) ([]byte, error) {
+		requested = append(requested, url)
+		if url == "https://raw.githubusercontent.com/actions/checkout/refs/tags/v4/action.yml" {
+			return []byte("name: Checkout\nruns:\n  using: node20\n  main: dist/index.js\n"), nil
+		}
+		return nil, &StatusError{StatusCode: 404}
+	}}
+	ar, err := ParseActionRef("actions/checkout@v4", Repository{})
+	assert.NoError(t, err)
+	wf, err := FetchActionWorkflow(context.Background(), client, ar)
+	assert.NoError(t, err)
+	assert.Equal(t, "Checkout", wf.Name)
+	assert.Equal(t, RefKindTag, wf.Ref.RefKind)
+	assert.Equal(t, []string{
+		"https://raw.githubusercontent.com/actions/checkout/refs/heads/v4/action.yml",
+		"https://raw.githubusercontent.com/actions/checkout/refs/tags/v4/action.yml",
+	}, requested)
+}
@@ -1,33 +1,57 @@
 package github
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Workflow represents a GitHub Actions workflow.
+// When the document was fetched from an action metadata file, Action is set and Jobs is empty.
 type Workflow struct {
-	Name string         `yaml:"name"`
-	URL  string         `yaml:"url"`
-	Jobs map[string]Job `yaml:"jobs"`
+	Name     string         `yaml:"name"`
+	URL      string         `yaml:"url"`
+	On       Triggers       `yaml:"on"`
+	Jobs     map[string]Job `yaml:"jobs"`
+	JobOrder []string       `yaml:"-"` // job IDs in YAML document order
+	Action   *Action        `yaml:"-"`
+	Ref      *ActionRef     `yaml:"-"` // reference the document was fetched from
 }
 
 // Job represents a job in a GitHub Actions workflow.
 type Job struct {
-	Needs NeedsList `yaml:"needs"`
-	Steps []Step    `yaml:"steps"`
-	Uses  string    `yaml:"uses"`
+	Name           string            `yaml:"name"`
+	Needs          NeedsList         `yaml:"needs"`
+	RunsOn         RunsOn            `yaml:"runs-on"`
+	If             string            `yaml:"if"`
+	Strategy       *Strategy         `yaml:"strategy"`
+	Environment    *Environment      `yaml:"environment"`
+	Concurrency    *Concurrency      `yaml:"concurrency"`
+	TimeoutMinutes string            `yaml:"timeout-minutes"`
+	Permissions    *Permissions      `yaml:"permissions"`
+	Outputs        map[string]string `yaml:"outputs"`
+	With           map[string]any    `yaml:"with"`
+	Secrets        *Secrets          `yaml:"secrets"`
+	Steps          []Step            `yaml:"steps"`
+	Uses           string            `yaml:"uses"`
 }
 
 // Step represents a step in a job.
 type Step struct {
-	Uses string `yaml:"uses"`
-	Name string `yaml:"name"`
-	Run  string `yaml:"run"`
+	ID              string `yaml:"id"`
+	Uses            string `yaml:"uses"`
+	Name            string `yaml:"name"`
+	Run             string `yaml:"run"`
+	If              string `yaml:"if"`
+	ContinueOnError string `yaml:"continue-on-error"`
+	Shell           string `yaml:"shell"`
 }
 
 // NeedsList handles both string and []string for the 'needs' field.
@@ -35,19 +59,53 @@ type NeedsList []string
 
 // ActionRef represents a parsed 'uses' reference in a workflow step.
 type ActionRef struct {
-	Type  string // "local", "remote", or "marketplace"
-	Owner string
-	Repo  string
-	Ref   string
-	Path  string
-	Raw   string // original uses string
+	Type   string // one of the UsesType constants
+	Target string // one of the UsesTarget constants
+	Owner  string
+	Repo   string
+	Ref    string
+	Path   string // path in the repository, or the image of docker references
+	Raw    string // original uses string
+	Dir    string // directory of the repository local references are read from, "" for the current directory
+
+	SHA     string // commit SHA the ref resolved to, when known
+	RefKind string // kind of ref (one of the RefKind constants), when known
 }
 
+// Node kinds of a UsesNode.
+const (
+	NodeKindRepository = "repository"
+	NodeKindWorkflow   = "workflow"
+	NodeKindJob        = "job"
+	NodeKindAction     = "action"
+	NodeKindStep       = "step"
+)
+
 // UsesNode representa um nó na árvore de dependências de uses.
 type UsesNode struct {
 	Name     string
 	UniqueID string // Novo campo para identificador único
+	Kind     string // one of the NodeKind constants
+	Uses     string // raw 'uses' reference, if any
+	Ref      *ActionRef
+	URL      string // source URL of the workflow or action the node was resolved from
+	Title    string // name of the workflow or action the node was resolved from, if it has one
 	Children []*UsesNode
+	Needs    []string // UniqueIDs of the sibling jobs this node depends on
+	Job      *Job     // job definition, set for job nodes
+	Step     *Step    // step definition, set for step nodes (see WithSteps)
+	Action   *Action  // metadata of the action the node was resolved to, if any
+	Previous string   // UniqueID of the step run before this step node, if any
+	Triggers Triggers // events that start the workflow, set for workflow nodes
+	Calls    []string // UniqueIDs of workflow nodes called by this node outside of its subtree
+
+	// Cycle is set when the reference of the node leads back to a workflow or action that is being expanded
+	// above it: it lists the references of the cycle, and CycleTo is the UniqueID of the node that workflow
+	// or action is expanded under. The node is not expanded again.
+	Cycle   []string
+	CycleTo string
+
+	Unresolved *ResolveError // set when the 'uses' reference of the node could not be resolved
 }
 
 // ParseWorkflowYAML parses the workflow YAML into a Workflow struct.
@@ -60,167 +118,389 @@ func ParseWorkflowYAML(url string, data []byte) (*Workflow, error) {
 	return &wf, nil
 }
 
-// ParseActionRef parses a 'uses' string and returns an ActionRef.
-// repoOwner, repoName, branch are used for resolving local actions.
-// Returns (ActionRef, true) if recognized, or (zero, false) if not.
-func ParseActionRef(uses, repoOwner, repoName, branch string) (ActionRef, bool) {
-	ar := ActionRef{Raw: uses}
-
-	// local action
-	if strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, ".github/") {
-		ar.Type = "local"
-		ar.Path = uses
-
-		slog.Debug("Identified a local action", "uses", uses, "path", ar.Path)
+// UnmarshalYAML decodes the workflow and records the document order of its jobs.
+func (w *Workflow) UnmarshalYAML(value *yaml.Node) error {
+	type plain Workflow
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	*w = Workflow(p)
+	w.JobOrder = mappingKeys(value, "jobs")
+	return nil
+}
 
-		return ar, true
+// JobNames returns the job IDs in YAML document order. When the order is unknown
+// (e.g. the workflow was built in code), the IDs are sorted alphabetically so that
+// the output is always stable.
+func (w *Workflow) JobNames() []string {
+	if len(w.JobOrder) == len(w.Jobs) {
+		return w.JobOrder
 	}
+	names := make([]string, 0, len(w.Jobs))
+	for name := range w.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-	// remote action: owner/repo/path@ref
-	re := regexp.MustCompile(`^([^/]+)/([^/]+)/(.*?)(?:@(.+))?$`)
-	matches := re.FindStringSubmatch(uses)
-	if len(matches) == 5 {
-		ar.Type = "remote"
-		ar.Owner = matches[1]
-		ar.Repo = matches[2]
-		ar.Path = matches[3]
-		ar.Ref = matches[4]
-		if ar.Ref == "" {
-			ar.Ref = "main"
+// mappingKeys returns, in document order, the keys of the mapping stored under key in the given mapping node.
+func mappingKeys(node *yaml.Node, key string) []string {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key || node.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		value := node.Content[i+1]
+		keys := make([]string, 0, len(value.Content)/2)
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			keys = append(keys, value.Content[j].Value)
 		}
+		return keys
 	}
-
-	slog.Debug("Identified a remote action", "uses", uses, "owner", ar.Owner, "repo", ar.Repo, "ref", ar.Ref, "path", ar.Path)
-
-	return ar, true
+	return nil
 }
 
-// FetchActionWorkflow tries to download and parse the action.yml or action.yaml for a given ActionRef.
-// Returns the parsed Workflow or nil if not found or not a composite action.
-func FetchActionWorkflow(client WorkflowDownloader, ar ActionRef) *Workflow {
+// FetchActionWorkflow tries to download and parse the reusable workflow, action.yml or action.yaml for a given ActionRef.
+// Returns the parsed Workflow (with Action set for action metadata files), or a *ResolveError describing why
+// the reference could not be resolved.
+func FetchActionWorkflow(ctx context.Context, client WorkflowDownloader, ar ActionRef) (*Workflow, error) {
 	var urls []string
 	switch ar.Type {
-	case "local":
-		urls = []string{ar.Path}
-	case "remote":
-		base := fmt.Sprintf("https://raw.githubusercontent.com/%s/%s", ar.Owner, ar.Repo)
-		urls = []string{
-			fmt.Sprintf("%s/refs/heads/%s/%s", base, ar.Ref, strings.TrimSuffix(ar.Path, "/")),
-			fmt.Sprintf("%s/refs/tags/%s/%s", base, ar.Ref, strings.TrimSuffix(ar.Path, "/")),
-			fmt.Sprintf("%s/refs/heads/%s/%s/action.yml", base, ar.Ref, strings.TrimSuffix(ar.Path, "/")),
-			fmt.Sprintf("%s/refs/tags/%s/%s/action.yml", base, ar.Ref, strings.TrimSuffix(ar.Path, "/")),
+	case UsesTypeLocal:
+		if ar.Owner != "" {
+			return fetchRemoteLocal(ctx, client, ar)
 		}
+		urls = documentPaths(ar, localPath(ar))
+	case UsesTypeRemote:
+		host := HostOf(client)
+		if fetcher, ok := client.(ContentsFetcher); ok {
+			wf, err := fetchContents(ctx, fetcher, host, ar)
+			var resolveErr *ResolveError
+			if err == nil || errors.As(err, &resolveErr) {
+				return wf, err
+			}
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) {
+				slog.Warn("Failed to fetch with the contents API, falling back to raw URLs", "uses", ar.Raw, "error", err)
+			} else {
+				slog.Debug("Failed to fetch with the contents API, falling back to raw URLs", "uses", ar.Raw, "error", err)
+			}
+		}
+		for _, p := range documentPaths(ar, strings.Trim(ar.Path, "/")) {
+			if ar.SHA != "" {
+				urls = append(urls, rawContentURL(host, ar.Owner, ar.Repo, ar.SHA, p))
+				continue
+			}
+			urls = append(urls,
+				rawContentURL(host, ar.Owner, ar.Repo, "refs/heads/"+ar.Ref, p),
+				rawContentURL(host, ar.Owner, ar.Repo, "refs/tags/"+ar.Ref, p))
+		}
+	case UsesTypeDocker:
+		// Docker images have nothing to expand.
+		return nil, nil
 	default:
-		return nil
+		return nil, &ResolveError{Uses: ar.Raw, Reason: UnresolvedInvalid}
 	}
 
 	slog.Debug("Fetching action workflow", "urls", urls)
 
-	skippedURLs := 0
+	// The most relevant failure is reported: a file that could not be parsed, then an access or
+	// server error, and "not found" only when every candidate is missing.
+	var parseErr, downloadErr, notFoundErr error
 	for _, url := range urls {
-		data, err := client.DownloadWorkflow(url)
+		data, err := client.DownloadWorkflow(ctx, url)
 		if err != nil {
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) || ctx.Err() != nil {
+				slog.Error("Failed to download workflow", "url", url, "error", err)
+				return nil, newResolveError(ar.Raw, err)
+			}
 			slog.Debug("Failed to download workflow", "url", url, "error", err)
-			skippedURLs++
-
+			if unresolvedReason(err) == UnresolvedNotFound {
+				notFoundErr = err
+			} else {
+				downloadErr = err
+			}
 			continue
 		}
-		wf, err := ParseWorkflowYAML(url, data)
-		if err == nil && wf != nil && len(wf.Jobs) > 0 {
-			return wf
+		switch {
+		case strings.Contains(url, "/refs/heads/"):
+			ar.RefKind = RefKindBranch
+		case strings.Contains(url, "/refs/tags/"):
+			ar.RefKind = RefKindTag
 		}
-
-		slog.Debug("Failed to parse workflow", "url", url, "error", err)
+		wf, err := parseActionDocument(url, data, ar)
+		if err == nil {
+			return wf, nil
+		}
+		parseErr = err
 	}
 
-	if skippedURLs == len(urls) {
+	switch {
+	case parseErr != nil:
+		return nil, &ResolveError{Uses: ar.Raw, Reason: UnresolvedParse, Err: parseErr}
+	case downloadErr != nil:
+		return nil, newResolveError(ar.Raw, downloadErr)
+	default:
 		slog.Warn("All urls failed to download workflow", "urls", urls)
+		return nil, newResolveError(ar.Raw, notFoundErr)
 	}
+}
 
-	return nil
+// fetchRemoteLocal fetches a local reference of a remote repository from that repository, at the ref of
+// the workflow it appears in.
+func fetchRemoteLocal(ctx context.Context, client WorkflowDownloader, ar ActionRef) (*Workflow, error) {
+	remote := ar
+	remote.Type = UsesTypeRemote
+	remote.Path = strings.TrimPrefix(ar.Path, "./")
+	wf, err := FetchActionWorkflow(ctx, client, remote)
+	if wf != nil && wf.Ref != nil {
+		ref := *wf.Ref
+		ref.Type = UsesTypeLocal
+		ref.Path = ar.Path
+		wf.Ref = &ref
+	}
+	return wf, err
 }
 
-// BuildUsesTree builds a hierarchical tree of uses dependencies starting from the given workflow.
-func BuildUsesTree(name string, wf *Workflow, fetcher func(string) *Workflow, depth int, visited map[string]bool) *UsesNode {
-	return buildUsesTreeRecursive(name, wf, fetcher, depth, visited, "")
+// localPath returns the filesystem path of a local reference, relative to the directory of its repository.
+func localPath(ar ActionRef) string {
+	if ar.Dir == "" {
+		return strings.TrimSuffix(ar.Path, "/")
+	}
+	return filepath.Join(ar.Dir, filepath.FromSlash(strings.TrimPrefix(ar.Path, "./")))
 }
 
-// buildUsesTreeRecursive é a versão recursiva que carrega o caminho até o nó.
-func buildUsesTreeRecursive(name string, wf *Workflow, fetcher func(string) *Workflow, depth int, visited map[string]bool, path string) *UsesNode {
-	if depth == 0 || wf == nil || visited[path+"/"+name] {
+// documentPaths returns the candidate paths of the document referenced by ar, located at p: the reusable
+// workflow file itself, or the action.yml or action.yaml metadata file of the action directory.
+func documentPaths(ar ActionRef, p string) []string {
+	if ar.IsWorkflow() {
+		return []string{p}
+	}
+	if p == "" || p == "." {
+		return []string{"action.yml", "action.yaml"}
+	}
+	return []string{p + "/action.yml", p + "/action.yaml"}
+}
+
+// parseActionDocument parses a downloaded reusable workflow or action metadata file referenced by ar.
+// Returns an error if data is neither.
+func parseActionDocument(url string, data []byte, ar ActionRef) (*Workflow, error) {
+	wf, err := ParseWorkflowYAML(url, data)
+	if err == nil && wf != nil && len(wf.Jobs) > 0 {
+		wf.Ref = &ar
+		return wf, nil
+	}
+
+	action, actionErr := ParseActionYAML(data)
+	if actionErr == nil {
+		slog.Debug("Parsed action metadata", "url", url, "using", action.Runs.Using, "steps", len(action.Runs.Steps))
+		return &Workflow{Name: action.Name, URL: url, Action: action, Ref: &ar}, nil
+	}
+
+	slog.Debug("Failed to parse workflow", "url", url, "error", err, "actionError", actionErr)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a workflow nor an action: %w", url, err)
+	}
+	return nil, fmt.Errorf("%s is neither a workflow with jobs nor an action: %w", url, actionErr)
+}
+
+// BuildUsesTree builds a hierarchical tree of uses dependencies starting from the given workflow.
+// Each 'uses' reference of a job or step is one level deeper than the document it appears in, and is only
+// expanded while the depth allows it: with depth 1 only the jobs and steps of wf are listed, with depth 2
+// the workflows and actions they reference are expanded too, and so on.
+// A reference leading back to a workflow or action that is being expanded closes a cycle: it is recorded
+// on the node (see UsesNode.Cycle) and not expanded again.
+// References are fetched with ctx; once it is done, they are no longer expanded.
+//
+// The references are resolved once per workflow or action into a graph of documents (see documentGraph),
+// and the tree is derived from that graph: a workflow or action used by several callers is listed under
+// each of them, but it is fetched and walked once.
+func BuildUsesTree(ctx context.Context, name string, wf *Workflow, fetcher func(context.Context, string) (*Workflow, error), depth int) *UsesNode {
+	if depth == 0 || wf == nil {
 		return nil
 	}
-	visited[path+"/"+name] = true
-	uniqueID := path + "/" + name
-	if path == "" {
-		uniqueID = name
+	g, doc := newDocumentGraph(ctx, wf, fetcher, depth)
+	root := &UsesNode{Name: name, UniqueID: name, Kind: NodeKindWorkflow, URL: wf.URL, Title: wf.Name, Triggers: wf.On}
+	var chain *expansion
+	root.Children = g.documentNodes(root.UniqueID, doc, depth, chain.push(wf, root.UniqueID, wf.URL))
+	return root
+}
+
+// documentNodes builds the nodes of the jobs of a workflow, or of the steps of a composite action.
+// chain is the expansion of the document.
+func (g *documentGraph) documentNodes(parentID string, doc *document, depth int, chain *expansion) []*UsesNode {
+	wf := doc.wf
+	if wf.Action != nil {
+		if wf.Action.IsComposite() {
+			return g.stepNodes(parentID, doc, wf.Action.Runs.Steps, depth, chain)
+		}
+		return nil
 	}
-	node := &UsesNode{Name: name, UniqueID: uniqueID}
-	for jobName, job := range wf.Jobs {
+	var nodes []*UsesNode
+	for _, jobName := range wf.JobNames() {
+		job := wf.Jobs[jobName]
+		jobNode := newJobNode(parentID, jobName, wf, job)
 		if job.Uses != "" {
-			child := &UsesNode{Name: jobName, UniqueID: uniqueID + "/" + jobName}
-			if fetcher != nil && depth > 1 {
-				childWf := fetcher(job.Uses)
-				if childWf != nil {
-					for subJobName, subJob := range childWf.Jobs {
-						if subJob.Uses != "" && fetcher != nil && depth > 2 {
-							subChildWf := fetcher(subJob.Uses)
-							subChild := &UsesNode{Name: subJobName, UniqueID: child.UniqueID + "/" + subJobName}
-							if subChildWf != nil {
-								subtree := buildUsesTreeRecursive(subJobName, subChildWf, fetcher, depth-2, visited, child.UniqueID)
-								if subtree != nil {
-									subChild.Children = subtree.Children
-								}
-							}
-							child.Children = append(child.Children, subChild)
-						} else {
-							child.Children = append(child.Children, &UsesNode{Name: subJobName, UniqueID: child.UniqueID + "/" + subJobName})
-						}
-					}
-				}
-			}
-			node.Children = append(node.Children, child)
+			g.expand(jobNode, doc, depth, chain)
+		} else {
+			jobNode.Children = g.stepNodes(jobNode.UniqueID, doc, job.Steps, depth, chain)
+		}
+		nodes = append(nodes, jobNode)
+	}
+	return nodes
+}
+
+// fetcherOf returns the fetcher of the references found in wf. The local reusable workflows called by a
+// document fetched with a remote reference are in the repository of that document, at the same ref: they
+// are fetched as owner/repo/path@ref references rather than against the repository of the root workflow.
+// Local actions are resolved in the repository checked out by the run, the one of the root workflow.
+func fetcherOf(wf *Workflow, fetcher func(context.Context, string) (*Workflow, error)) func(context.Context, string) (*Workflow, error) {
+	if fetcher == nil || wf.Ref == nil || wf.Ref.Type != UsesTypeRemote {
+		return fetcher
+	}
+	ref := *wf.Ref
+	return func(ctx context.Context, uses string) (*Workflow, error) {
+		return fetcher(ctx, ref.localUses(uses))
+	}
+}
+
+// localUses returns the local reusable workflow reference uses, found in a document of the repository of
+// ar, as a remote reference to the same ref. Other references, local actions included, are returned as is.
+func (ar ActionRef) localUses(uses string) string {
+	if !strings.HasPrefix(uses, "./") || strings.Contains(uses, "@") || !isWorkflowFile(uses) {
+		return uses
+	}
+	return ar.Owner + "/" + ar.Repo + "/" + strings.TrimPrefix(uses, "./") + "@" + ar.Ref
+}
+
+// stepNodes builds the nodes for the steps of doc that have 'uses', expanding composite actions and
+// nested references while depth allows it. Steps are identified by their position ('#1', '#2', ...) under
+// their job or action, so that a reference used twice gets a node for each step.
+func (g *documentGraph) stepNodes(parentID string, doc *document, steps []Step, depth int, chain *expansion) []*UsesNode {
+	var nodes []*UsesNode
+	for i, step := range steps {
+		if step.Uses == "" {
 			continue
 		}
-		// If not a reusable, just add the job and its steps
-		jobNode := &UsesNode{Name: jobName, UniqueID: uniqueID + "/" + jobName}
-		for _, step := range job.Steps {
-			if step.Uses != "" {
-				stepNode := &UsesNode{Name: step.Uses, UniqueID: jobNode.UniqueID + "/" + step.Uses}
-				if fetcher != nil && depth > 1 {
-					childWf := fetcher(step.Uses)
-					if childWf != nil {
-						subtree := buildUsesTreeRecursive(step.Uses, childWf, fetcher, depth-1, visited, jobNode.UniqueID)
-						if subtree != nil {
-							stepNode.Children = subtree.Children
-						}
-					}
-				}
-				jobNode.Children = append(jobNode.Children, stepNode)
-			}
+		stepNode := &UsesNode{Name: step.Uses, UniqueID: stepID(parentID, i), Kind: NodeKindAction, Uses: step.Uses}
+		g.expand(stepNode, doc, depth, chain)
+		nodes = append(nodes, stepNode)
+	}
+	return nodes
+}
+
+// expand resolves the reference of the node, found in doc at the given depth, and adds the jobs or steps
+// of the workflow or action it references as children, one level deeper, unless that workflow or action
+// is already being expanded. chain is the expansion of doc.
+func (g *documentGraph) expand(n *UsesNode, doc *document, depth int, chain *expansion) {
+	ref := g.resolve(n, doc, depth > 1)
+	if ref == nil || ref.doc == nil {
+		return
+	}
+	if ancestor := chain.find(ref.wf); ancestor != nil {
+		n.Cycle = chain.cycle(ancestor, n.Uses)
+		n.CycleTo = ancestor.nodeID
+		return
+	}
+	n.Children = g.documentNodes(n.UniqueID, ref.doc, depth-1, chain.push(ref.wf, n.UniqueID, n.Uses))
+}
+
+// newJobNode creates the node of a job of wf.
+func newJobNode(parentID, name string, wf *Workflow, job Job) *UsesNode {
+	return &UsesNode{
+		Name:     name,
+		UniqueID: parentID + "/" + name,
+		Kind:     NodeKindJob,
+		Uses:     job.Uses,
+		Needs:    needsIDs(parentID, wf, job),
+		Job:      &job,
+	}
+}
+
+// resolve returns the resolution in doc of the reference of the node when expand is true, recording on
+// the node its source, or the reason why it is unresolved: the fetch error, or the depth limit when expand
+// is false. Returns nil when nothing was fetched. Nodes are left as is without a fetcher.
+func (g *documentGraph) resolve(n *UsesNode, doc *document, expand bool) *reference {
+	if g.fetcher == nil {
+		return nil
+	}
+	if !expand {
+		n.Unresolved = &ResolveError{Uses: n.Uses, Reason: UnresolvedDepthLimit}
+		return nil
+	}
+	ref := doc.refs[n.Uses]
+	if ref == nil {
+		return nil
+	}
+	if ref.err != nil {
+		n.Unresolved = newResolveError(n.Uses, ref.err)
+		return nil
+	}
+	if ref.wf == nil {
+		return nil
+	}
+	n.setSource(ref.wf)
+	return ref
+}
+
+// setSource records the reference, URL, name and action metadata of the document the node was resolved to.
+func (n *UsesNode) setSource(wf *Workflow) {
+	n.Ref = wf.Ref
+	n.URL = wf.URL
+	n.Title = wf.Name
+	n.Action = wf.Action
+	if wf.Action != nil {
+		n.Title = wf.Action.Name
+	}
+}
+
+// needsIDs returns the UniqueIDs of the jobs in wf that the given job needs.
+// Needs pointing at jobs that do not exist in the workflow are ignored.
+func needsIDs(parentID string, wf *Workflow, job Job) []string {
+	var ids []string
+	for _, need := range job.Needs {
+		if _, ok := wf.Jobs[need]; !ok {
+			slog.Debug("Ignoring unknown job in needs", "workflow", wf.URL, "needs", need)
+			continue
 		}
-		node.Children = append(node.Children, jobNode)
+		ids = append(ids, parentID+"/"+need)
 	}
-	return node
+	return ids
 }
 
 // CollectAllUses recursively collects all 'uses' from a workflow and its referenced actions, up to a given depth.
-func CollectAllUses(wf *Workflow, fetcher func(string) *Workflow, depth int) []string {
-	if depth == 0 || wf == nil {
+// References leading back to a workflow that is being collected are listed but not followed again.
+func CollectAllUses(ctx context.Context, wf *Workflow, fetcher func(context.Context, string) (*Workflow, error), depth int) []string {
+	if wf == nil {
+		return nil
+	}
+	var chain *expansion
+	return collectAllUses(ctx, wf, fetcher, depth, chain.push(wf, "", ""))
+}
+
+// collectAllUses collects the 'uses' of wf, whose expansion is chain.
+func collectAllUses(ctx context.Context, wf *Workflow, fetcher func(context.Context, string) (*Workflow, error), depth int, chain *expansion) []string {
+	if depth == 0 {
 		return nil
 	}
 
 	slog.Info("Getting all uses", "workflow", wf.Name, "url", wf.URL)
+	fetcher = fetcherOf(wf, fetcher)
 
 	var uses []string
-	for _, job := range wf.Jobs {
+	for _, jobName := range wf.JobNames() {
+		job := wf.Jobs[jobName]
 		// Job-level uses
 		if job.Uses != "" {
 			uses = append(uses, job.Uses)
 			if fetcher != nil {
-				childWf := fetcher(job.Uses)
-				if childWf != nil {
-					uses = append(uses, CollectAllUses(childWf, fetcher, depth-1)...)
+				if childWf, err := fetcher(ctx, job.Uses); err == nil && childWf != nil && chain.find(childWf) == nil {
+					uses = append(uses, collectAllUses(ctx, childWf, fetcher, depth-1, chain.push(childWf, "", job.Uses))...)
 				}
 			}
 		}
@@ -243,10 +523,15 @@ func (n *NeedsList) UnmarshalYAML(value *yaml.Node) error {
 	return fmt.Errorf("invalid needs field: %v", value.Value)
 }
 
-// ExtractRepoInfoRegex returns the regex to extract owner, repo, branch from a raw.githubusercontent.com or github.com/blob URL.
-func ExtractRepoInfoRegex() *regexp.Regexp {
+// ExtractRepoInfoRegex returns the regex to extract owner, repo, branch from a file URL of the given GitHub host.
+func ExtractRepoInfoRegex(host string) *regexp.Regexp {
 	// Supports:
 	// https://raw.githubusercontent.com/owner/repo/branch/path/to/file.yml
 	// https://github.com/owner/repo/blob/branch/path/to/file.yml
-	return regexp.MustCompile(`https://(?:raw\.githubusercontent\.com|github\.com)/([^/]+)/([^/]+)/(?:blob/)?([^/]+)/`)
+	// and, for GitHub Enterprise Server:
+	// https://ghes.example.com/owner/repo/blob/branch/path/to/file.yml
+	// https://ghes.example.com/owner/repo/raw/branch/path/to/file.yml
+	// https://ghes.example.com/raw/owner/repo/branch/path/to/file.yml
+	// https://raw.ghes.example.com/owner/repo/branch/path/to/file.yml
+	return regexp.MustCompile(repoURLPattern(host) + `([^/]+)/`)
 }
@@ -1,6 +1,9 @@
 package github
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -66,86 +69,29 @@ jobs:
 	assert.Equal(t, "owner/repo/.github/workflows/workflow.yml@main", wf.Jobs["call_another_workflow"].Uses)
 }
 
-func TestParseActionRef_Remote(t *testing.T) {
-	ar, ok := ParseActionRef("octocat/myaction/path@v1", "", "", "")
-	assert.True(t, ok)
-	assert.Equal(t, "remote", ar.Type)
-	assert.Equal(t, "octocat", ar.Owner)
-	assert.Equal(t, "myaction", ar.Repo)
-	assert.Equal(t, "path", ar.Path)
-	assert.Equal(t, "v1", ar.Ref)
-}
-
-func TestParseActionRef_Remote_WithRef(t *testing.T) {
-	ar, ok := ParseActionRef("octocat/myaction/path@v1", "", "", "")
-	assert.True(t, ok)
-	assert.Equal(t, "remote", ar.Type)
-	assert.Equal(t, "octocat", ar.Owner)
-	assert.Equal(t, "myaction", ar.Repo)
-	assert.Equal(t, "path", ar.Path)
-	assert.Equal(t, "v1", ar.Ref)
-}
-
-func TestParseActionRef_Remote_WithoutRef(t *testing.T) {
-	ar, ok := ParseActionRef("octocat/myaction/path", "", "", "")
-	assert.True(t, ok)
-	assert.Equal(t, "remote", ar.Type)
-	assert.Equal(t, "octocat", ar.Owner)
-	assert.Equal(t, "myaction", ar.Repo)
-	assert.Equal(t, "path", ar.Path)
-	assert.Equal(t, "main", ar.Ref) // default branch
-}
-
-func TestParseActionRef_Remote_WithSubdirAndRef(t *testing.T) {
-	ar, ok := ParseActionRef("octocat/myaction/some/dir/file.yml@feature-branch", "", "", "")
-	assert.True(t, ok)
-	assert.Equal(t, "remote", ar.Type)
-	assert.Equal(t, "octocat", ar.Owner)
-	assert.Equal(t, "myaction", ar.Repo)
-	assert.Equal(t, "some/dir/file.yml", ar.Path)
-	assert.Equal(t, "feature-branch", ar.Ref)
-}
-
-func TestParseActionRef_Remote_WithSubdirNoRef(t *testing.T) {
-	ar, ok := ParseActionRef("octocat/myaction/some/dir/file.yml", "", "", "")
-	assert.True(t, ok)
-	assert.Equal(t, "remote", ar.Type)
-	assert.Equal(t, "octocat", ar.Owner)
-	assert.Equal(t, "myaction", ar.Repo)
-	assert.Equal(t, "some/dir/file.yml", ar.Path)
-	assert.Equal(t, "main", ar.Ref)
-}
-
-func TestParseActionRef_Invalid(t *testing.T) {
-	ar, ok := ParseActionRef("echo hello", "", "", "")
-	assert.True(t, ok)
-	assert.Equal(t, "", ar.Type)
-	assert.Equal(t, "", ar.Owner)
-	assert.Equal(t, "", ar.Repo)
-	assert.Equal(t, "", ar.Path)
-	assert.Equal(t, "", ar.Ref)
-}
-
-func TestParseActionRef_Marketplace(t *testing.T) {
-	ar, ok := ParseActionRef("actions/checkout@v2", "", "", "")
-	assert.True(t, ok)
-	assert.Equal(t, "", ar.Type)
-	assert.Equal(t, "", ar.Owner)
-	assert.Equal(t, "", ar.Repo)
-	assert.Equal(t, "", ar.Path)
-	assert.Equal(t, "", ar.Ref)
+func TestParseWorkflowYAML_JobOrder(t *testing.T) {
+	yamlData := []byte(`
+jobs:
+  zeta: {}
+  alpha: {}
+  mid: {}
+`)
+	wf, err := ParseWorkflowYAML("", yamlData)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zeta", "alpha", "mid"}, wf.JobOrder)
+	assert.Equal(t, []string{"zeta", "alpha", "mid"}, wf.JobNames())
 }
 
-func TestParseActionRef_Unrecognized(t *testing.T) {
-	_, ok := ParseActionRef("echo hello", "", "", "")
-	assert.True(t, ok)
+func TestWorkflowJobNames_SortedWithoutOrder(t *testing.T) {
+	wf := &Workflow{Jobs: map[string]Job{"c": {}, "a": {}, "b": {}}}
+	assert.Equal(t, []string{"a", "b", "c"}, wf.JobNames())
 }
 
 type mockClient struct {
 	DownloadWorkflowFunc func(url string) ([]byte, error)
 }
 
-func (m *mockClient) DownloadWorkflow(url string) ([]byte, error) {
+func (m *mockClient) DownloadWorkflow(ctx context.Context, url string) ([]byte, error) {
 	return m.DownloadWorkflowFunc(url)
 }
 
@@ -156,9 +102,15 @@ func TestFetchActionWorkflow_MarketplaceOrUnknown(t *testing.T) {
 		},
 	}
 	ar := ActionRef{Type: "marketplace"}
-	assert.Nil(t, FetchActionWorkflow(client, ar))
+	wf, err := FetchActionWorkflow(context.Background(), client, ar)
+	assert.Nil(t, wf)
+	assert.Error(t, err)
 	ar.Type = "unknown"
-	assert.Nil(t, FetchActionWorkflow(client, ar))
+	wf, err = FetchActionWorkflow(context.Background(), client, ar)
+	assert.Nil(t, wf)
+	var resolveErr *ResolveError
+	assert.ErrorAs(t, err, &resolveErr)
+	assert.Equal(t, UnresolvedInvalid, resolveErr.Reason)
 }
 
 func TestFetchActionWorkflow_ErrorCases(t *testing.T) {
@@ -168,9 +120,9 @@ func TestFetchActionWorkflow_ErrorCases(t *testing.T) {
 		},
 	}
 	ar := ActionRef{Type: "local", Path: "some/path"}
-	wf := FetchActionWorkflow(client, ar)
-	if wf != nil {
-		t.Errorf("Expected nil when download fails")
+	wf, err := FetchActionWorkflow(context.Background(), client, ar)
+	if wf != nil || err == nil {
+		t.Errorf("Expected an error when download fails")
 	}
 
 	client2 := &mockClient{
@@ -178,9 +130,10 @@ func TestFetchActionWorkflow_ErrorCases(t *testing.T) {
 			return []byte("invalid: [unclosed"), nil
 		},
 	}
-	wf2 := FetchActionWorkflow(client2, ar)
-	if wf2 != nil {
-		t.Errorf("Expected nil when parsing fails")
+	wf2, err := FetchActionWorkflow(context.Background(), client2, ar)
+	var resolveErr *ResolveError
+	if wf2 != nil || !errors.As(err, &resolveErr) || resolveErr.Reason != UnresolvedParse {
+		t.Errorf("Expected a parse error when parsing fails, got %v", err)
 	}
 
 	client3 := &mockClient{
@@ -188,12 +141,146 @@ func TestFetchActionWorkflow_ErrorCases(t *testing.T) {
 			return []byte("name: Empty"), nil
 		},
 	}
-	wf3 := FetchActionWorkflow(client3, ar)
-	if wf3 != nil {
+	wf3, err := FetchActionWorkflow(context.Background(), client3, ar)
+	if wf3 != nil || err == nil {
 		t.Errorf("Expected nil when workflow has no jobs")
 	}
 }
 
+func TestFetchActionWorkflow_CompositeAction(t *testing.T) {
+	var requested []string
+	client := &mockClient{
+		DownloadWorkflowFunc: func(url string) ([]byte, error) {
+			requested = append(requested, url)
+			if url == "./actions/setup/action.yml" {
+				return []byte("name: Setup\nruns:\n  using: composite\n  steps:\n    - uses: actions/checkout@v4\n"), nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	wf, err := FetchActionWorkflow(context.Background(), client, ActionRef{Type: "local", Path: "./actions/setup"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Setup", wf.Name)
+	assert.Equal(t, "./actions/setup/action.yml", wf.URL)
+	assert.NotNil(t, wf.Action)
+	assert.True(t, wf.Action.IsComposite())
+	assert.Equal(t, &ActionRef{Type: "local", Path: "./actions/setup"}, wf.Ref)
+	assert.Equal(t, []string{"./actions/setup/action.yml"}, requested)
+}
+
+func TestBuildUsesTree_SharedDocumentsFetchedOnce(t *testing.T) {
+	// Each workflow calls the next one from two jobs: the tree lists 2^levels leaves, but each workflow
+	// is fetched and walked once.
+	const levels = 12
+	workflows := map[string]*Workflow{}
+	for i := range levels + 1 {
+		url := fmt.Sprintf("w%d.yml", i)
+		wf := &Workflow{URL: url, Jobs: map[string]Job{"leaf": {}}}
+		if i < levels {
+			next := fmt.Sprintf("./w%d.yml", i+1)
+			wf.Jobs = map[string]Job{"a": {Uses: next}, "b": {Uses: next}}
+		}
+		workflows["./"+url] = wf
+	}
+	calls := 0
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		calls++
+		return workflows[uses], nil
+	}
+
+	tree := BuildUsesTree(context.Background(), "root", workflows["./w0.yml"], fetcher, levels+1)
+	assert.Equal(t, levels, calls)
+
+	leaves := 0
+	var visit func(n *UsesNode)
+	visit = func(n *UsesNode) {
+		if len(n.Children) == 0 {
+			leaves++
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	visit(tree)
+	assert.Equal(t, 1<<levels, leaves)
+	assert.Equal(t, "root/b/a", tree.Children[1].Children[0].UniqueID)
+
+	// The prefetch walks each workflow once too.
+	calls = 0
+	Prefetch(context.Background(), workflows["./w0.yml"], fetcher, levels+1, 1)
+	assert.Equal(t, 2*levels, calls)
+}
+
+func TestBuildUsesTree_KindsAndSources(t *testing.T) {
+	wf := &Workflow{
+		URL: "ci.yml",
+		Jobs: map[string]Job{
+			"build": {Steps: []Step{{Uses: "./actions/setup"}}},
+			"call":  {Uses: "./.github/workflows/reusable.yml"},
+		},
+	}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		ref := &ActionRef{Type: "local", Path: uses}
+		if uses == "./actions/setup" {
+			return &Workflow{URL: "actions/setup/action.yml", Ref: ref, Action: &Action{Runs: ActionRuns{Using: "node20"}}}, nil
+		}
+		return &Workflow{URL: "reusable.yml", Ref: ref, Jobs: map[string]Job{"a": {}}}, nil
+	}
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 2)
+	assert.Equal(t, NodeKindWorkflow, tree.Kind)
+	assert.Equal(t, "ci.yml", tree.URL)
+
+	build, call := tree.Children[0], tree.Children[1]
+	assert.Equal(t, NodeKindJob, build.Kind)
+	assert.Equal(t, NodeKindAction, build.Children[0].Kind)
+	assert.Equal(t, "./actions/setup", build.Children[0].Uses)
+	assert.Equal(t, "actions/setup/action.yml", build.Children[0].URL)
+	assert.Equal(t, "./actions/setup", build.Children[0].Ref.Path)
+
+	assert.Equal(t, NodeKindJob, call.Kind)
+	assert.Equal(t, "./.github/workflows/reusable.yml", call.Uses)
+	assert.Equal(t, "reusable.yml", call.URL)
+	assert.Equal(t, NodeKindJob, call.Children[0].Kind)
+}
+
+func TestBuildUsesTree_CompositeAction(t *testing.T) {
+	wf := &Workflow{
+		Jobs: map[string]Job{
+			"build": {Steps: []Step{{Uses: "./actions/setup"}, {Run: "make"}}},
+		},
+	}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		switch uses {
+		case "./actions/setup":
+			return &Workflow{Action: &Action{Runs: ActionRuns{Using: "composite", Steps: []Step{
+				{Uses: "./actions/nested"},
+				{Run: "echo hi"},
+			}}}}, nil
+		case "./actions/nested":
+			return &Workflow{Action: &Action{Runs: ActionRuns{Using: "composite", Steps: []Step{
+				{Uses: "actions/checkout@v4"},
+			}}}}, nil
+		}
+		return nil, nil
+	}
+
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 2)
+	job := tree.Children[0]
+	assert.Len(t, job.Children, 1)
+	setup := job.Children[0]
+	assert.Equal(t, "./actions/setup", setup.Name)
+	assert.Len(t, setup.Children, 1)
+	nested := setup.Children[0]
+	assert.Equal(t, "./actions/nested", nested.Name)
+	assert.Equal(t, "root/build/#1/#1", nested.UniqueID)
+	assert.Empty(t, nested.Children, "depth limit should stop expansion")
+
+	tree = BuildUsesTree(context.Background(), "root", wf, fetcher, 3)
+	nested = tree.Children[0].Children[0].Children[0]
+	assert.Len(t, nested.Children, 1)
+	assert.Equal(t, "actions/checkout@v4", nested.Children[0].Name)
+}
+
 func TestBuildUsesTree_Simple(t *testing.T) {
 	wf := &Workflow{
 		Jobs: map[string]Job{
@@ -201,7 +288,7 @@ func TestBuildUsesTree_Simple(t *testing.T) {
 			"b": {},
 		},
 	}
-	tree := BuildUsesTree("root", wf, nil, 2, map[string]bool{})
+	tree := BuildUsesTree(context.Background(), "root", wf, nil, 2)
 	if tree == nil {
 		t.Errorf("Expected non-nil tree")
 		return
@@ -222,13 +309,13 @@ func TestBuildUsesTree_WithReusable(t *testing.T) {
 			"main": {Uses: "reusable.yml@main"},
 		},
 	}
-	fetcher := func(uses string) *Workflow {
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
 		if uses == "reusable.yml@main" {
-			return &Workflow{Jobs: map[string]Job{"a": {}, "b": {}}}
+			return &Workflow{Jobs: map[string]Job{"a": {}, "b": {}}}, nil
 		}
-		return nil
+		return nil, nil
 	}
-	tree := BuildUsesTree("root", wf, fetcher, 2, map[string]bool{})
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 2)
 	if tree == nil || len(tree.Children) != 1 {
 		t.Errorf("Expected 1 child, got %v", len(tree.Children))
 	}
@@ -238,16 +325,68 @@ func TestBuildUsesTree_WithReusable(t *testing.T) {
 	}
 }
 
+func TestBuildUsesTree_Needs(t *testing.T) {
+	wf := &Workflow{
+		Jobs: map[string]Job{
+			"build":  {},
+			"deploy": {Needs: NeedsList{"build", "missing"}},
+			"call":   {Uses: "reusable.yml@main", Needs: NeedsList{"deploy"}},
+		},
+	}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		return &Workflow{Jobs: map[string]Job{"a": {}, "b": {Needs: NeedsList{"a"}}}}, nil
+	}
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 2)
+	needs := map[string][]string{}
+	for _, child := range tree.Children {
+		needs[child.UniqueID] = child.Needs
+		for _, sub := range child.Children {
+			needs[sub.UniqueID] = sub.Needs
+		}
+	}
+	assert.Empty(t, needs["root/build"])
+	assert.Equal(t, []string{"root/build"}, needs["root/deploy"])
+	assert.Equal(t, []string{"root/deploy"}, needs["root/call"])
+	assert.Equal(t, []string{"root/call/a"}, needs["root/call/b"])
+}
+
+func TestBuildUsesTree_DocumentOrder(t *testing.T) {
+	wf, err := ParseWorkflowYAML("", []byte(`
+jobs:
+  test: {}
+  lint: {}
+  build:
+    uses: reusable.yml@main
+`))
+	assert.NoError(t, err)
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		child, err := ParseWorkflowYAML(uses, []byte("jobs:\n  z: {}\n  y: {}\n  x: {}\n"))
+		assert.NoError(t, err)
+		return child, nil
+	}
+	for i := 0; i < 10; i++ {
+		tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 2)
+		var names []string
+		for _, child := range tree.Children {
+			names = append(names, child.Name)
+			for _, sub := range child.Children {
+				names = append(names, sub.Name)
+			}
+		}
+		assert.Equal(t, []string{"test", "lint", "build", "z", "y", "x"}, names)
+	}
+}
+
 func TestBuildUsesTree_DepthLimit(t *testing.T) {
 	wf := &Workflow{
 		Jobs: map[string]Job{
 			"main": {Uses: "reusable.yml@main"},
 		},
 	}
-	fetcher := func(uses string) *Workflow {
-		return &Workflow{Jobs: map[string]Job{"a": {}, "b": {}}}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		return &Workflow{Jobs: map[string]Job{"a": {}, "b": {}}}, nil
 	}
-	tree := BuildUsesTree("root", wf, fetcher, 1, map[string]bool{})
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 1)
 	if tree == nil || len(tree.Children) != 1 {
 		t.Errorf("Expected 1 child at depth 1, got %v", len(tree.Children))
 	}
@@ -262,13 +401,13 @@ func TestBuildUsesTree_Cycle(t *testing.T) {
 			"main": {Uses: "reusable.yml@main"},
 		},
 	}
-	fetcher := func(uses string) *Workflow {
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
 		if uses == "reusable.yml@main" {
-			return wf
+			return wf, nil
 		}
-		return nil
+		return nil, nil
 	}
-	tree := BuildUsesTree("root", wf, fetcher, 5, map[string]bool{})
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 5)
 	if tree == nil || len(tree.Children) != 1 {
 		t.Errorf("Expected 1 child, got %v", len(tree.Children))
 	}
@@ -276,7 +415,7 @@ func TestBuildUsesTree_Cycle(t *testing.T) {
 
 func TestBuildUsesTree_EmptyWorkflow(t *testing.T) {
 	wf := &Workflow{Jobs: map[string]Job{}}
-	tree := BuildUsesTree("root", wf, nil, 2, map[string]bool{})
+	tree := BuildUsesTree(context.Background(), "root", wf, nil, 2)
 	if tree == nil {
 		t.Errorf("Expected non-nil tree for empty workflow")
 		return
@@ -293,7 +432,7 @@ func TestCollectAllUses_JobLevel(t *testing.T) {
 			"job2": {Uses: "actions/checkout@v4"},
 		},
 	}
-	uses := CollectAllUses(wf, nil, 2)
+	uses := CollectAllUses(context.Background(), wf, nil, 2)
 	if len(uses) != 2 {
 		t.Errorf("Expected 2 uses, got %v", uses)
 	}
@@ -308,14 +447,14 @@ func TestCollectAllUses_DepthLimit(t *testing.T) {
 			"main": {Uses: "reusable.yml@main"},
 		},
 	}
-	fetcher := func(uses string) *Workflow {
-		return &Workflow{Jobs: map[string]Job{"a": {Uses: "other.yml@main"}}}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		return &Workflow{Jobs: map[string]Job{"a": {Uses: "other.yml@main"}}}, nil
 	}
-	uses := CollectAllUses(wf, fetcher, 1)
+	uses := CollectAllUses(context.Background(), wf, fetcher, 1)
 	if len(uses) != 1 || uses[0] != "reusable.yml@main" {
 		t.Errorf("Expected only the first level use, got %v", uses)
 	}
-	uses2 := CollectAllUses(wf, fetcher, 2)
+	uses2 := CollectAllUses(context.Background(), wf, fetcher, 2)
 	if len(uses2) != 2 {
 		t.Errorf("Expected two uses with depth 2, got %v", uses2)
 	}
@@ -331,7 +470,7 @@ func contains(slice []string, s string) bool {
 }
 
 func TestExtractRepoInfoRegex(t *testing.T) {
-	re := ExtractRepoInfoRegex()
+	re := ExtractRepoInfoRegex(DefaultHost)
 	url := "https://raw.githubusercontent.com/owner/repo/branch/path/to/file.yml"
 	matches := re.FindStringSubmatch(url)
 	if len(matches) != 4 {
@@ -341,3 +480,61 @@ func TestExtractRepoInfoRegex(t *testing.T) {
 		t.Errorf("Unexpected extraction: %v", matches)
 	}
 }
+
+func TestExtractRepoInfoRegex_Enterprise(t *testing.T) {
+	re := ExtractRepoInfoRegex("ghes.example.com")
+	urls := []string{
+		"https://ghes.example.com/owner/repo/blob/branch/path/to/file.yml",
+		"https://ghes.example.com/owner/repo/raw/branch/path/to/file.yml",
+		"https://ghes.example.com/raw/owner/repo/branch/path/to/file.yml",
+		"https://raw.ghes.example.com/owner/repo/branch/path/to/file.yml",
+	}
+	for _, url := range urls {
+		matches := re.FindStringSubmatch(url)
+		if len(matches) != 4 || matches[1] != "owner" || matches[2] != "repo" || matches[3] != "branch" {
+			t.Errorf("Unexpected extraction for %s: %v", url, matches)
+		}
+	}
+	if re.MatchString("https://raw.githubusercontent.com/owner/repo/branch/path/to/file.yml") {
+		t.Errorf("Expected github.com URLs not to match an enterprise host")
+	}
+}
+
+func TestBuildUsesTree_UniformDepth(t *testing.T) {
+	wf := &Workflow{Jobs: map[string]Job{"call": {Uses: "./.github/workflows/reusable.yml"}}}
+	fetcher := func(ctx context.Context, uses string) (*Workflow, error) {
+		switch uses {
+		case "./.github/workflows/reusable.yml":
+			return &Workflow{Jobs: map[string]Job{"build": {Steps: []Step{{Uses: "./actions/setup"}, {Run: "make"}}}}}, nil
+		case "./actions/setup":
+			return &Workflow{Action: &Action{Runs: ActionRuns{Using: "composite", Steps: []Step{{Uses: "actions/checkout@v4"}}}}}, nil
+		}
+		return nil, nil
+	}
+
+	// The steps of the jobs of a reusable workflow are listed like the steps of the root workflow.
+	tree := BuildUsesTree(context.Background(), "root", wf, fetcher, 2)
+	build := tree.Children[0].Children[0]
+	assert.Equal(t, "root/call/build", build.UniqueID)
+	assert.Len(t, build.Children, 1)
+	setup := build.Children[0]
+	assert.Equal(t, "root/call/build/#1", setup.UniqueID)
+	assert.Empty(t, setup.Children)
+	assert.Equal(t, UnresolvedDepthLimit, setup.Unresolved.Reason)
+
+	tree = BuildUsesTree(context.Background(), "root", wf, fetcher, 3)
+	setup = tree.Children[0].Children[0].Children[0]
+	assert.Nil(t, setup.Unresolved)
+	assert.Len(t, setup.Children, 1)
+	assert.Equal(t, UnresolvedDepthLimit, setup.Children[0].Unresolved.Reason)
+}
+
+func TestActionRef_LocalUses(t *testing.T) {
+	ar := ActionRef{Type: UsesTypeRemote, Owner: "org", Repo: "shared", Ref: "v1"}
+	assert.Equal(t, "org/shared/.github/workflows/b.yml@v1", ar.localUses("./.github/workflows/b.yml"))
+	assert.Equal(t, "org/shared/.github/workflows/c.yaml@v1", ar.localUses("./.github/workflows/c.yaml"))
+	assert.Equal(t, "./actions/setup/", ar.localUses("./actions/setup/"))
+	assert.Equal(t, "./", ar.localUses("./"))
+	assert.Equal(t, "actions/checkout@v4", ar.localUses("actions/checkout@v4"))
+	assert.Equal(t, "./action@v2", ar.localUses("./action@v2"))
+}
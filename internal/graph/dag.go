@@ -0,0 +1,156 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+)
+
+// FromDAG converts a UsesNode tree into a Graph with one node per unique reference. A job calling a
+// reusable workflow points with a uses edge at the workflow node, a step using an action (see
+// github.UsesNode.WithSteps) points at the action node, and so does a job or composite action whose steps
+// are not listed; the jobs or steps of the referenced workflow or action are listed once,
+// under that node, whatever the number of callers. References are identified by the URL they were resolved
+// from, or by their raw 'uses' string when they were not resolved.
+//
+// A reference closing a cycle is a cycle edge from its caller to the node of the workflow or action it
+// leads back to.
+//
+// Depth is the number of 'uses' hops between the root and the first occurrence of the node.
+func FromDAG(root *github.UsesNode) *Graph {
+	g := &Graph{SchemaVersion: SchemaVersion, Nodes: []Node{}, Edges: []Edge{}}
+	if root == nil {
+		return g
+	}
+	b := &dagBuilder{g: g, ids: map[string]string{}, docs: map[string]string{}, added: map[string]bool{}, edges: map[Edge]bool{}}
+	b.add(root, root.UniqueID, 0)
+	setDefaultTypes(g)
+
+	walk(root, func(node *github.UsesNode) {
+		for _, need := range node.Needs {
+			b.addMappedEdge(need, node.UniqueID, EdgeTypeNeeds)
+		}
+	})
+	walk(root, func(node *github.UsesNode) {
+		if node.Previous != "" {
+			b.addMappedEdge(node.Previous, node.UniqueID, EdgeTypeNext)
+		}
+	})
+	walk(root, func(node *github.UsesNode) {
+		for _, call := range node.Calls {
+			b.addMappedEdge(node.UniqueID, call, EdgeTypeUses)
+		}
+	})
+	addTriggers(g, root, func(node *github.UsesNode) (string, bool) {
+		id, ok := b.ids[node.UniqueID]
+		return id, ok
+	})
+
+	return g
+}
+
+// dagBuilder merges the nodes of a UsesNode tree that stand for the same reference.
+type dagBuilder struct {
+	g     *Graph
+	ids   map[string]string // graph node ID of each tree node that was added, by UniqueID
+	docs  map[string]string // graph node ID of the workflow or action expanded under each tree node, by UniqueID
+	added map[string]bool   // IDs of the graph nodes
+	edges map[Edge]bool
+}
+
+// add adds the tree node with the given graph ID, and its children unless the node was already added
+// through another caller.
+func (b *dagBuilder) add(node *github.UsesNode, id string, depth int) {
+	b.ids[node.UniqueID] = id
+	if b.added[id] {
+		return
+	}
+	b.added[id] = true
+
+	// The children of a job calling a reusable workflow are the jobs of that workflow, and the children of
+	// a step using an action are the steps of that action: they belong to the workflow or action node,
+	// shared by every caller.
+	owner, ownerDepth := id, depth
+	if node.Uses != "" && node.Kind != github.NodeKindAction {
+		caller := *node
+		caller.Ref, caller.URL, caller.Title, caller.Unresolved = nil, "", "", nil
+		b.addNode(newNode(&caller, depth), id)
+
+		if node.CycleTo != "" {
+			b.addEdge(id, b.docs[node.CycleTo], EdgeTypeCycle)
+			return
+		}
+		owner, ownerDepth = referenceID(node), depth+1
+		b.docs[node.UniqueID] = owner
+		b.addEdge(id, owner, EdgeTypeUses)
+		if b.added[owner] {
+			return
+		}
+		b.added[owner] = true
+		reference := newNode(node, ownerDepth)
+		reference.Type = NodeTypeWorkflow
+		if node.Kind == github.NodeKindStep {
+			reference.Type = NodeTypeAction
+		}
+		reference.Name = node.Uses
+		reference.Attributes = nil
+		b.addNode(reference, owner)
+	} else {
+		b.addNode(newNode(node, depth), id)
+		b.docs[node.UniqueID] = id
+	}
+
+	for _, child := range node.Children {
+		if child.CycleTo != "" && child.Kind == github.NodeKindAction {
+			b.ids[child.UniqueID] = b.docs[child.CycleTo]
+			b.addEdge(owner, b.docs[child.CycleTo], EdgeTypeCycle)
+			continue
+		}
+		if child.Uses != "" && child.Kind == github.NodeKindAction {
+			ref := referenceID(child)
+			b.addEdge(owner, ref, EdgeTypeUses)
+			b.add(child, ref, ownerDepth+1)
+			continue
+		}
+		// Nodes under a reference are identified relatively to it, other nodes keep their tree ID.
+		childID := child.UniqueID
+		if owner != node.UniqueID {
+			childID = owner + "/" + strings.TrimPrefix(child.UniqueID, node.UniqueID+"/")
+		}
+		b.addEdge(owner, childID, EdgeTypeContains)
+		b.add(child, childID, ownerDepth)
+	}
+}
+
+// addNode adds n to the graph with the given ID.
+func (b *dagBuilder) addNode(n Node, id string) {
+	n.ID = id
+	b.g.Nodes = append(b.g.Nodes, n)
+}
+
+// addEdge adds an edge between two graph nodes, once.
+func (b *dagBuilder) addEdge(from, to, typ string) {
+	e := Edge{From: from, To: to, Type: typ}
+	if !b.edges[e] {
+		b.edges[e] = true
+		b.g.Edges = append(b.g.Edges, e)
+	}
+}
+
+// addMappedEdge adds an edge between the graph nodes of two tree nodes, if both were added.
+func (b *dagBuilder) addMappedEdge(from, to, typ string) {
+	fromID, fromOK := b.ids[from]
+	toID, toOK := b.ids[to]
+	if fromOK && toOK {
+		b.addEdge(fromID, toID, typ)
+	}
+}
+
+// referenceID returns the ID of the graph node of the reference of a tree node: the URL it was resolved
+// from, or its raw 'uses' string when it was not resolved.
+func referenceID(node *github.UsesNode) string {
+	if node.URL != "" {
+		return node.URL
+	}
+	return node.Uses
+}
@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// sharedTree returns a workflow whose two jobs call the same reusable workflow, and whose jobs use the same action.
+func sharedTree() *github.UsesNode {
+	reusable := func(caller string) *github.UsesNode {
+		id := "workflow/" + caller
+		return &github.UsesNode{
+			Name: caller, UniqueID: id, Kind: github.NodeKindJob, Uses: "./.github/workflows/build.yml",
+			Ref: &github.ActionRef{Type: "local", Target: "workflow", Path: "./.github/workflows/build.yml"}, URL: ".github/workflows/build.yml",
+			Job: &github.Job{Uses: "./.github/workflows/build.yml"},
+			Children: []*github.UsesNode{
+				{Name: "compile", UniqueID: id + "/compile", Kind: github.NodeKindJob, Job: &github.Job{RunsOn: github.RunsOn{Labels: []string{"ubuntu-latest"}}},
+					Children: []*github.UsesNode{
+						{Name: "actions/checkout@v4", UniqueID: id + "/compile/actions/checkout@v4", Kind: github.NodeKindAction, Uses: "actions/checkout@v4",
+							Unresolved: &github.ResolveError{Uses: "actions/checkout@v4", Reason: github.UnresolvedDepthLimit}},
+					}},
+				{Name: "test", UniqueID: id + "/test", Kind: github.NodeKindJob, Needs: []string{id + "/compile"}},
+			},
+		}
+	}
+	return &github.UsesNode{
+		Name:     "workflow",
+		UniqueID: "workflow",
+		Kind:     github.NodeKindWorkflow,
+		URL:      "ci.yml",
+		Triggers: github.Triggers{{Event: "push"}},
+		Children: []*github.UsesNode{
+			{Name: "lint", UniqueID: "workflow/lint", Kind: github.NodeKindJob, Children: []*github.UsesNode{
+				{Name: "actions/checkout@v4", UniqueID: "workflow/lint/actions/checkout@v4", Kind: github.NodeKindAction, Uses: "actions/checkout@v4",
+					Unresolved: &github.ResolveError{Uses: "actions/checkout@v4", Reason: github.UnresolvedDepthLimit}},
+			}},
+			reusable("linux"),
+			reusable("windows"),
+		},
+	}
+}
+
+func TestFromDAG(t *testing.T) {
+	g := FromDAG(sharedTree())
+	assert.Equal(t, SchemaVersion, g.SchemaVersion)
+	checkout := Node{ID: "actions/checkout@v4", Type: NodeTypeAction, Name: "actions/checkout@v4", Uses: "actions/checkout@v4", Depth: 1,
+		Details: []string{"unresolved: depth limit"}, Unresolved: github.UnresolvedDepthLimit}
+	assert.Equal(t, []Node{
+		{ID: "workflow", Type: NodeTypeWorkflow, Name: "workflow", URL: "ci.yml"},
+		{ID: "workflow/lint", Type: NodeTypeJob, Name: "lint"},
+		checkout,
+		{ID: "workflow/linux", Type: NodeTypeJob, Name: "linux", Uses: "./.github/workflows/build.yml"},
+		{ID: ".github/workflows/build.yml", Type: NodeTypeWorkflow, Name: "./.github/workflows/build.yml", Uses: "./.github/workflows/build.yml",
+			Ref: &Ref{Type: "local", Target: "workflow", Path: "./.github/workflows/build.yml"}, URL: ".github/workflows/build.yml", Depth: 1},
+		{ID: ".github/workflows/build.yml/compile", Type: NodeTypeJob, Name: "compile", Depth: 1, Attributes: map[string]string{"runs-on": "ubuntu-latest"}},
+		{ID: ".github/workflows/build.yml/test", Type: NodeTypeJob, Name: "test", Depth: 1},
+		{ID: "workflow/windows", Type: NodeTypeJob, Name: "windows", Uses: "./.github/workflows/build.yml"},
+		{ID: "workflow#push", Type: NodeTypeTrigger, Name: "push"},
+	}, g.Nodes)
+	assert.Equal(t, []Edge{
+		{From: "workflow", To: "workflow/lint", Type: EdgeTypeContains},
+		{From: "workflow/lint", To: "actions/checkout@v4", Type: EdgeTypeUses},
+		{From: "workflow", To: "workflow/linux", Type: EdgeTypeContains},
+		{From: "workflow/linux", To: ".github/workflows/build.yml", Type: EdgeTypeUses},
+		{From: ".github/workflows/build.yml", To: ".github/workflows/build.yml/compile", Type: EdgeTypeContains},
+		{From: ".github/workflows/build.yml/compile", To: "actions/checkout@v4", Type: EdgeTypeUses},
+		{From: ".github/workflows/build.yml", To: ".github/workflows/build.yml/test", Type: EdgeTypeContains},
+		{From: "workflow", To: "workflow/windows", Type: EdgeTypeContains},
+		{From: "workflow/windows", To: ".github/workflows/build.yml", Type: EdgeTypeUses},
+		{From: ".github/workflows/build.yml/compile", To: ".github/workflows/build.yml/test", Type: EdgeTypeNeeds},
+		{From: "workflow#push", To: "workflow", Type: EdgeTypeTriggers},
+	}, g.Edges)
+}
+
+func TestFromDAG_Nil(t *testing.T) {
+	g := FromDAG(nil)
+	assert.Empty(t, g.Nodes)
+	assert.Empty(t, g.Edges)
+}
+
+func TestFromDAG_KeepsTreeIDsOutsideOfReferences(t *testing.T) {
+	root := &github.UsesNode{Name: "repo", UniqueID: "repo", Kind: github.NodeKindRepository, Children: []*github.UsesNode{
+		{Name: "ci.yml", UniqueID: "ci.yml", Kind: github.NodeKindWorkflow, Children: []*github.UsesNode{
+			{Name: "call", UniqueID: "ci.yml/call", Kind: github.NodeKindJob, Calls: []string{"build.yml"}},
+		}},
+		{Name: "build.yml", UniqueID: "build.yml", Kind: github.NodeKindWorkflow},
+	}}
+	tree, dag := FromTree(root), FromDAG(root)
+	assert.Equal(t, tree.Nodes, dag.Nodes)
+	assert.ElementsMatch(t, tree.Edges, dag.Edges)
+}
+
+// cyclicTree returns a workflow calling a.yml, which calls b.yml, which calls a.yml again, and a composite
+// action using itself.
+func cyclicTree() *github.UsesNode {
+	return &github.UsesNode{Name: "workflow", UniqueID: "workflow", Kind: github.NodeKindWorkflow, URL: "ci.yml", Children: []*github.UsesNode{
+		{Name: "a", UniqueID: "workflow/a", Kind: github.NodeKindJob, Uses: "./a.yml", URL: "a.yml", Children: []*github.UsesNode{
+			{Name: "x", UniqueID: "workflow/a/x", Kind: github.NodeKindJob, Uses: "./b.yml", URL: "b.yml", Children: []*github.UsesNode{
+				{Name: "y", UniqueID: "workflow/a/x/y", Kind: github.NodeKindJob, Uses: "./a.yml", URL: "a.yml",
+					Cycle: []string{"./a.yml", "./b.yml", "./a.yml"}, CycleTo: "workflow/a"},
+			}},
+		}},
+		{Name: "build", UniqueID: "workflow/build", Kind: github.NodeKindJob, Children: []*github.UsesNode{
+			{Name: "./loop", UniqueID: "workflow/build/./loop", Kind: github.NodeKindAction, Uses: "./loop", URL: "loop/action.yml", Children: []*github.UsesNode{
+				{Name: "./loop", UniqueID: "workflow/build/./loop/./loop", Kind: github.NodeKindAction, Uses: "./loop", URL: "loop/action.yml",
+					Cycle: []string{"./loop", "./loop"}, CycleTo: "workflow/build/./loop"},
+			}},
+		}},
+	}}
+}
+
+// cycleEdges returns the cycle edges of g.
+func cycleEdges(g *Graph) []Edge {
+	var edges []Edge
+	for _, e := range g.Edges {
+		if e.Type == EdgeTypeCycle {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+func TestFromTree_Cycle(t *testing.T) {
+	assert.Equal(t, []Edge{
+		{From: "workflow/a/x/y", To: "workflow/a", Type: EdgeTypeCycle},
+		{From: "workflow/build/./loop/./loop", To: "workflow/build/./loop", Type: EdgeTypeCycle},
+	}, cycleEdges(FromTree(cyclicTree())))
+}
+
+func TestFromDAG_Cycle(t *testing.T) {
+	g := FromDAG(cyclicTree())
+	assert.Equal(t, []Edge{
+		{From: "b.yml/y", To: "a.yml", Type: EdgeTypeCycle},
+		{From: "loop/action.yml", To: "loop/action.yml", Type: EdgeTypeCycle},
+	}, cycleEdges(g))
+	for _, n := range g.Nodes {
+		assert.NotEqual(t, "workflow/a/x/y", n.ID)
+	}
+}
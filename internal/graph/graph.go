@@ -0,0 +1,328 @@
+// Package graph provides the typed dependency graph produced from a UsesNode tree, in a tree view where every
+// reference is listed under each of its callers, or a DAG view with one node per unique reference.
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the version of the published graph schema (docs/schema/graph.v1.json).
+// It is bumped on any backwards incompatible change.
+const SchemaVersion = "1"
+
+// Node types.
+const (
+	NodeTypeRepository = github.NodeKindRepository
+	NodeTypeWorkflow   = github.NodeKindWorkflow
+	NodeTypeJob        = github.NodeKindJob
+	NodeTypeAction     = github.NodeKindAction
+	NodeTypeStep       = github.NodeKindStep
+	NodeTypeTrigger    = "trigger"
+)
+
+// Edge types.
+const (
+	EdgeTypeContains = "contains"
+	EdgeTypeNeeds    = "needs"
+	EdgeTypeUses     = "uses"
+	EdgeTypeTriggers = "triggers"
+	// EdgeTypeCycle is the back-edge of a reference leading to a workflow or action that is being expanded.
+	EdgeTypeCycle = "cycle"
+	// EdgeTypeNext links a step to the step run after it.
+	EdgeTypeNext = "next"
+)
+
+// Views of the graph.
+const (
+	ViewTree = "tree"
+	ViewDAG  = "dag"
+)
+
+// Detail levels of the graph.
+const (
+	// DetailJobs lists the jobs, and the steps that use an action.
+	DetailJobs = "jobs"
+	// DetailSteps lists every step, in order (see github.UsesNode.WithSteps).
+	DetailSteps = "steps"
+	// DetailFull lists every step, and the diagrams show the attributes of the nodes.
+	DetailFull = "full"
+)
+
+// Graph is the resolved dependency graph of a workflow (or of a whole repository).
+type Graph struct {
+	SchemaVersion string `json:"schemaVersion" yaml:"schemaVersion"`
+	Nodes         []Node `json:"nodes" yaml:"nodes"`
+	Edges         []Edge `json:"edges" yaml:"edges"`
+}
+
+// Node is a workflow, job, action, step or trigger of the graph.
+type Node struct {
+	ID   string `json:"id" yaml:"id"`
+	Type string `json:"type" yaml:"type"`
+	Name string `json:"name" yaml:"name"`
+	Uses string `json:"uses,omitempty" yaml:"uses,omitempty"`
+	Ref  *Ref   `json:"ref,omitempty" yaml:"ref,omitempty"`
+	URL  string `json:"url,omitempty" yaml:"url,omitempty"`
+	// Title is the name of the workflow or action the node was resolved from, if it has one.
+	Title   string   `json:"title,omitempty" yaml:"title,omitempty"`
+	Depth   int      `json:"depth" yaml:"depth"`
+	Details []string `json:"details,omitempty" yaml:"details,omitempty"`
+	// Attributes are the properties of the node definition, e.g. the runner of a job.
+	Attributes map[string]string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// Unresolved is the reason why the 'uses' reference of the node could not be resolved (one of the
+	// github.Unresolved constants), if any.
+	Unresolved string `json:"unresolved,omitempty" yaml:"unresolved,omitempty"`
+}
+
+// Ref is a resolved 'uses' reference.
+type Ref struct {
+	Type   string `json:"type" yaml:"type"`
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	Owner  string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Repo   string `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Ref    string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	SHA    string `json:"sha,omitempty" yaml:"sha,omitempty"`
+	Kind   string `json:"kind,omitempty" yaml:"kind,omitempty"`
+}
+
+// Edge is a typed link between two nodes.
+type Edge struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// FromTree converts a UsesNode tree into a Graph. Nodes are listed depth first, followed by trigger nodes;
+// edges are listed by type (contains/uses, needs, next, calls, cycles, triggers) in the same order.
+//
+// Depth is the number of 'uses' hops between the root and the node: the jobs of the root workflow have
+// depth 0, the jobs of a reusable workflow they call have depth 1, and so on.
+func FromTree(root *github.UsesNode) *Graph {
+	g := &Graph{SchemaVersion: SchemaVersion, Nodes: []Node{}, Edges: []Edge{}}
+	if root == nil {
+		return g
+	}
+	seen := make(map[string]bool)
+
+	var addNodes func(node *github.UsesNode, depth int)
+	addNodes = func(node *github.UsesNode, depth int) {
+		if node == nil || seen[node.UniqueID] {
+			return
+		}
+		seen[node.UniqueID] = true
+		g.Nodes = append(g.Nodes, newNode(node, depth))
+		childDepth := depth
+		if node.Uses != "" {
+			childDepth++
+		}
+		for _, child := range node.Children {
+			addNodes(child, childDepth)
+		}
+	}
+	addNodes(root, 0)
+	setDefaultTypes(g)
+
+	addEdge := func(from, to, typ string) {
+		if seen[from] && seen[to] {
+			g.Edges = append(g.Edges, Edge{From: from, To: to, Type: typ})
+		}
+	}
+	walk(root, func(node *github.UsesNode) {
+		typ := EdgeTypeContains
+		if node.Uses != "" {
+			typ = EdgeTypeUses
+		}
+		for _, child := range node.Children {
+			addEdge(node.UniqueID, child.UniqueID, typ)
+		}
+	})
+	walk(root, func(node *github.UsesNode) {
+		for _, need := range node.Needs {
+			addEdge(need, node.UniqueID, EdgeTypeNeeds)
+		}
+	})
+	walk(root, func(node *github.UsesNode) {
+		if node.Previous != "" {
+			addEdge(node.Previous, node.UniqueID, EdgeTypeNext)
+		}
+	})
+	walk(root, func(node *github.UsesNode) {
+		for _, call := range node.Calls {
+			addEdge(node.UniqueID, call, EdgeTypeUses)
+		}
+	})
+	walk(root, func(node *github.UsesNode) {
+		if node.CycleTo != "" {
+			addEdge(node.UniqueID, node.CycleTo, EdgeTypeCycle)
+		}
+	})
+	addTriggers(g, root, func(node *github.UsesNode) (string, bool) {
+		return node.UniqueID, seen[node.UniqueID]
+	})
+
+	return g
+}
+
+// setDefaultTypes sets the type of the nodes of trees built without kinds: the root is a workflow and
+// everything else a job.
+func setDefaultTypes(g *Graph) {
+	for i := range g.Nodes {
+		if g.Nodes[i].Type == "" {
+			g.Nodes[i].Type = NodeTypeJob
+			if i == 0 {
+				g.Nodes[i].Type = NodeTypeWorkflow
+			}
+		}
+	}
+}
+
+// addTriggers adds a node for each trigger of the workflow nodes of the tree, linked to the graph node the
+// workflow node is mapped to by id. Workflow nodes without a graph node are skipped.
+func addTriggers(g *Graph, root *github.UsesNode, id func(*github.UsesNode) (string, bool)) {
+	walk(root, func(node *github.UsesNode) {
+		to, ok := id(node)
+		if !ok {
+			return
+		}
+		for _, trigger := range node.Triggers {
+			from := to + "#" + trigger.Event
+			g.Nodes = append(g.Nodes, Node{ID: from, Type: NodeTypeTrigger, Name: trigger.Event, Details: trigger.Details()})
+			g.Edges = append(g.Edges, Edge{From: from, To: to, Type: EdgeTypeTriggers})
+		}
+	})
+}
+
+// newNode converts a single UsesNode.
+func newNode(node *github.UsesNode, depth int) Node {
+	n := Node{
+		ID:    node.UniqueID,
+		Type:  node.Kind,
+		Name:  node.Name,
+		Uses:  node.Uses,
+		URL:   node.URL,
+		Title: node.Title,
+		Depth: depth,
+	}
+	if node.Ref != nil {
+		n.Ref = &Ref{Type: node.Ref.Type, Target: node.Ref.Target, Owner: node.Ref.Owner, Repo: node.Ref.Repo, Ref: node.Ref.Ref, Path: node.Ref.Path,
+			SHA: node.Ref.SHA, Kind: node.Ref.RefKind}
+	}
+	if node.Job != nil {
+		n.Attributes = jobAttributes(node.Job)
+	}
+	if node.Step != nil {
+		n.Attributes = stepAttributes(node.Step)
+	}
+	if node.Unresolved != nil {
+		n.Unresolved = node.Unresolved.Reason
+		n.Details = append(n.Details, "unresolved: "+node.Unresolved.Reason)
+	}
+	return n
+}
+
+// jobAttributes returns the attributes of a job definition that are set.
+func jobAttributes(job *github.Job) map[string]string {
+	attrs := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+	set("runs-on", job.RunsOn.String())
+	set("if", job.If)
+	if job.Environment != nil {
+		set("environment", job.Environment.Name)
+	}
+	set("timeout-minutes", job.TimeoutMinutes)
+	if s := job.Strategy; s != nil {
+		if s.Matrix != nil {
+			set("strategy.matrix", s.Matrix.String())
+		}
+		set("strategy.fail-fast", s.FailFast)
+		set("strategy.max-parallel", s.MaxParallel)
+	}
+	if c := job.Concurrency; c != nil {
+		set("concurrency", c.Group)
+		set("concurrency.cancel-in-progress", c.CancelInProgress)
+	}
+	if job.Permissions != nil {
+		set("permissions", job.Permissions.String())
+	}
+	set("outputs", strings.Join(sortedKeys(job.Outputs), ", "))
+	with := make([]string, 0, len(job.With))
+	for _, key := range sortedKeys(job.With) {
+		with = append(with, fmt.Sprintf("%s=%v", key, job.With[key]))
+	}
+	set("with", strings.Join(with, ", "))
+	if job.Secrets != nil {
+		set("secrets", job.Secrets.String())
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// sortedKeys returns the keys of the map in alphabetical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stepAttributes returns the attributes of a step definition that are set.
+func stepAttributes(step *github.Step) map[string]string {
+	attrs := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+	set("id", step.ID)
+	set("if", step.If)
+	set("continue-on-error", step.ContinueOnError)
+	set("shell", step.Shell)
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// walk visits every node of the tree in depth first order.
+func walk(node *github.UsesNode, visit func(*github.UsesNode)) {
+	if node == nil {
+		return
+	}
+	visit(node)
+	for _, child := range node.Children {
+		walk(child, visit)
+	}
+}
+
+// JSON returns the indented JSON encoding of the graph.
+func (g *Graph) JSON() (string, error) {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode graph as JSON: %w", err)
+	}
+	return string(data), nil
+}
+
+// YAML returns the YAML encoding of the graph.
+func (g *Graph) YAML() (string, error) {
+	data, err := yaml.Marshal(g)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode graph as YAML: %w", err)
+	}
+	return string(data), nil
+}
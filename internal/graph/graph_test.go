@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func sampleTree() *github.UsesNode {
+	return &github.UsesNode{
+		Name:     "workflow",
+		UniqueID: "workflow",
+		Kind:     github.NodeKindWorkflow,
+		URL:      "ci.yml",
+		Triggers: github.Triggers{{Event: "push", Branches: []string{"main"}}},
+		Children: []*github.UsesNode{
+			{Name: "build", UniqueID: "workflow/build", Kind: github.NodeKindJob, Children: []*github.UsesNode{
+				{
+					Name:     "owner/repo/setup@v1",
+					UniqueID: "workflow/build/owner/repo/setup@v1",
+					Kind:     github.NodeKindAction,
+					Uses:     "owner/repo/setup@v1",
+					Ref:      &github.ActionRef{Type: "remote", Target: "action", Owner: "owner", Repo: "repo", Ref: "v1", Path: "setup"},
+					URL:      "https://raw.githubusercontent.com/owner/repo/refs/tags/v1/setup/action.yml",
+					Children: []*github.UsesNode{
+						{Name: "actions/checkout@v4", UniqueID: "workflow/build/owner/repo/setup@v1/actions/checkout@v4", Kind: github.NodeKindAction, Uses: "actions/checkout@v4",
+							Unresolved: &github.ResolveError{Uses: "actions/checkout@v4", Reason: github.UnresolvedDepthLimit}},
+					},
+				},
+			}},
+			{Name: "deploy", UniqueID: "workflow/deploy", Kind: github.NodeKindJob, Needs: []string{"workflow/build"}},
+		},
+	}
+}
+
+func TestFromTree(t *testing.T) {
+	g := FromTree(sampleTree())
+	assert.Equal(t, SchemaVersion, g.SchemaVersion)
+	assert.Equal(t, []Node{
+		{ID: "workflow", Type: NodeTypeWorkflow, Name: "workflow", URL: "ci.yml"},
+		{ID: "workflow/build", Type: NodeTypeJob, Name: "build"},
+		{
+			ID:    "workflow/build/owner/repo/setup@v1",
+			Type:  NodeTypeAction,
+			Name:  "owner/repo/setup@v1",
+			Uses:  "owner/repo/setup@v1",
+			Ref:   &Ref{Type: "remote", Target: "action", Owner: "owner", Repo: "repo", Ref: "v1", Path: "setup"},
+			URL:   "https://raw.githubusercontent.com/owner/repo/refs/tags/v1/setup/action.yml",
+			Depth: 0,
+		},
+		{ID: "workflow/build/owner/repo/setup@v1/actions/checkout@v4", Type: NodeTypeAction, Name: "actions/checkout@v4", Uses: "actions/checkout@v4", Depth: 1,
+			Details: []string{"unresolved: depth limit"}, Unresolved: github.UnresolvedDepthLimit},
+		{ID: "workflow/deploy", Type: NodeTypeJob, Name: "deploy"},
+		{ID: "workflow#push", Type: NodeTypeTrigger, Name: "push", Details: []string{"branches: main"}},
+	}, g.Nodes)
+	assert.Equal(t, []Edge{
+		{From: "workflow", To: "workflow/build", Type: EdgeTypeContains},
+		{From: "workflow", To: "workflow/deploy", Type: EdgeTypeContains},
+		{From: "workflow/build", To: "workflow/build/owner/repo/setup@v1", Type: EdgeTypeContains},
+		{From: "workflow/build/owner/repo/setup@v1", To: "workflow/build/owner/repo/setup@v1/actions/checkout@v4", Type: EdgeTypeUses},
+		{From: "workflow/build", To: "workflow/deploy", Type: EdgeTypeNeeds},
+		{From: "workflow#push", To: "workflow", Type: EdgeTypeTriggers},
+	}, g.Edges)
+}
+
+func TestFromTree_JobAttributes(t *testing.T) {
+	wf, err := github.ParseWorkflowYAML("ci.yml", []byte(`
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    timeout-minutes: 30
+    environment: production
+    concurrency:
+      group: build-${{ github.ref }}
+      cancel-in-progress: true
+    permissions:
+      id-token: write
+      contents: read
+    strategy:
+      fail-fast: false
+      max-parallel: 2
+      matrix:
+        os: [ubuntu-latest, windows-latest]
+        exclude:
+          - os: windows-latest
+    outputs:
+      version: ${{ steps.version.outputs.value }}
+      digest: ${{ steps.push.outputs.digest }}
+  release:
+    uses: ./.github/workflows/release.yml
+    with:
+      dry-run: true
+      channel: stable
+    secrets: inherit
+    concurrency: release
+`))
+	assert.NoError(t, err)
+	g := FromTree(github.BuildUsesTree(context.Background(), "ci", wf, nil, 1))
+
+	attributes := map[string]map[string]string{}
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypeJob {
+			attributes[n.Name] = n.Attributes
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"runs-on":                        "ubuntu-latest",
+		"timeout-minutes":                "30",
+		"environment":                    "production",
+		"concurrency":                    "build-${{ github.ref }}",
+		"concurrency.cancel-in-progress": "true",
+		"permissions":                    "contents: read, id-token: write",
+		"strategy.matrix":                "os: ubuntu-latest, windows-latest; exclude: 1",
+		"strategy.fail-fast":             "false",
+		"strategy.max-parallel":          "2",
+		"outputs":                        "digest, version",
+	}, attributes["build"])
+	assert.Equal(t, map[string]string{
+		"concurrency": "release",
+		"with":        "channel=stable, dry-run=true",
+		"secrets":     "inherit",
+	}, attributes["release"])
+}
+
+func TestFromTree_Nil(t *testing.T) {
+	g := FromTree(nil)
+	assert.Empty(t, g.Nodes)
+	assert.Empty(t, g.Edges)
+}
+
+func TestGraph_JSONAndYAML(t *testing.T) {
+	g := FromTree(sampleTree())
+
+	out, err := g.JSON()
+	assert.NoError(t, err)
+	var fromJSON Graph
+	assert.NoError(t, json.Unmarshal([]byte(out), &fromJSON))
+	assert.Equal(t, *g, fromJSON)
+	assert.Contains(t, out, `"schemaVersion": "1"`)
+
+	out, err = g.YAML()
+	assert.NoError(t, err)
+	var fromYAML Graph
+	assert.NoError(t, yaml.Unmarshal([]byte(out), &fromYAML))
+	assert.Equal(t, *g, fromYAML)
+}
+
+func TestPublishedSchema(t *testing.T) {
+	data, err := os.ReadFile("../../docs/schema/graph.v" + SchemaVersion + ".json")
+	assert.NoError(t, err)
+
+	var schema struct {
+		Properties struct {
+			SchemaVersion struct {
+				Const string `json:"const"`
+			} `json:"schemaVersion"`
+		} `json:"properties"`
+		Defs struct {
+			Node struct {
+				Properties struct {
+					Type struct {
+						Enum []string `json:"enum"`
+					} `json:"type"`
+					Unresolved struct {
+						Enum []string `json:"enum"`
+					} `json:"unresolved"`
+				} `json:"properties"`
+			} `json:"node"`
+			Edge struct {
+				Properties struct {
+					Type struct {
+						Enum []string `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+			} `json:"edge"`
+		} `json:"$defs"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &schema))
+	assert.Equal(t, SchemaVersion, schema.Properties.SchemaVersion.Const)
+	assert.ElementsMatch(t, []string{NodeTypeRepository, NodeTypeWorkflow, NodeTypeJob, NodeTypeAction, NodeTypeStep, NodeTypeTrigger}, schema.Defs.Node.Properties.Type.Enum)
+	assert.ElementsMatch(t, []string{github.UnresolvedNotFound, github.UnresolvedAuth, github.UnresolvedRateLimit, github.UnresolvedParse,
+		github.UnresolvedInvalid, github.UnresolvedDepthLimit, github.UnresolvedError}, schema.Defs.Node.Properties.Unresolved.Enum)
+	assert.ElementsMatch(t, []string{EdgeTypeContains, EdgeTypeUses, EdgeTypeNeeds, EdgeTypeNext, EdgeTypeTriggers, EdgeTypeCycle}, schema.Defs.Edge.Properties.Type.Enum)
+}
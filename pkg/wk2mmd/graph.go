@@ -0,0 +1,91 @@
+package wk2mmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/leocomelli/wk2mmd/internal/graph"
+	"gopkg.in/yaml.v3"
+)
+
+// Graph is the machine-readable dependency graph. It follows the published JSON schema
+// (docs/schema/graph.v1.json).
+type Graph struct {
+	SchemaVersion string      `json:"schemaVersion" yaml:"schemaVersion"`
+	Nodes         []GraphNode `json:"nodes" yaml:"nodes"`
+	Edges         []GraphEdge `json:"edges" yaml:"edges"`
+}
+
+// GraphNode is a workflow, job, action, step or trigger of a Graph.
+type GraphNode struct {
+	ID      string    `json:"id" yaml:"id"`
+	Type    string    `json:"type" yaml:"type"`
+	Name    string    `json:"name" yaml:"name"`
+	Uses    string    `json:"uses,omitempty" yaml:"uses,omitempty"`
+	Ref     *GraphRef `json:"ref,omitempty" yaml:"ref,omitempty"`
+	URL     string    `json:"url,omitempty" yaml:"url,omitempty"`
+	Title   string    `json:"title,omitempty" yaml:"title,omitempty"`
+	Depth   int       `json:"depth" yaml:"depth"`
+	Details []string  `json:"details,omitempty" yaml:"details,omitempty"`
+	// Attributes are the properties of the node definition, e.g. the runner of a job.
+	Attributes map[string]string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// Unresolved is the reason why the 'uses' reference of the node could not be resolved (one of the
+	// Unresolved constants), if any.
+	Unresolved string `json:"unresolved,omitempty" yaml:"unresolved,omitempty"`
+}
+
+// GraphRef is the parsed 'uses' reference of a GraphNode.
+type GraphRef struct {
+	Type   string `json:"type" yaml:"type"`
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	Owner  string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Repo   string `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Ref    string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	SHA    string `json:"sha,omitempty" yaml:"sha,omitempty"`
+	Kind   string `json:"kind,omitempty" yaml:"kind,omitempty"` // branch, tag or commit
+}
+
+// GraphEdge is a typed link between two nodes of a Graph.
+type GraphEdge struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// JSON returns the indented JSON encoding of the graph.
+func (g *Graph) JSON() (string, error) {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode graph as JSON: %w", err)
+	}
+	return string(data), nil
+}
+
+// YAML returns the YAML encoding of the graph.
+func (g *Graph) YAML() (string, error) {
+	data, err := yaml.Marshal(g)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode graph as YAML: %w", err)
+	}
+	return string(data), nil
+}
+
+// newGraph returns the public model of g.
+func newGraph(g *graph.Graph) *Graph {
+	public := &Graph{SchemaVersion: g.SchemaVersion, Nodes: make([]GraphNode, 0, len(g.Nodes)), Edges: make([]GraphEdge, 0, len(g.Edges))}
+	for _, n := range g.Nodes {
+		node := GraphNode{ID: n.ID, Type: n.Type, Name: n.Name, Uses: n.Uses, URL: n.URL, Title: n.Title, Depth: n.Depth,
+			Details: append([]string(nil), n.Details...), Attributes: maps.Clone(n.Attributes), Unresolved: n.Unresolved}
+		if n.Ref != nil {
+			ref := GraphRef(*n.Ref)
+			node.Ref = &ref
+		}
+		public.Nodes = append(public.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		public.Edges = append(public.Edges, GraphEdge(e))
+	}
+	return public
+}
@@ -0,0 +1,333 @@
+package wk2mmd
+
+import (
+	"errors"
+	"maps"
+	"strings"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+)
+
+// Workflow is a parsed workflow, or an action metadata file when Action is set.
+type Workflow struct {
+	Name     string
+	URL      string     // URL or local path the document was read from
+	Triggers []string   // events that start the workflow, in document order
+	Jobs     []Job      // in document order
+	Action   *Action    // set for action metadata files
+	Ref      *ActionRef // reference the document was resolved from, nil for a parsed workflow
+}
+
+// Job is a job of a workflow. Fields that may hold expressions, e.g. FailFast, are kept as written.
+type Job struct {
+	ID             string
+	Name           string
+	Needs          []string // IDs of the jobs this job depends on
+	RunsOn         string   // runner labels or group, as written in the workflow
+	If             string
+	Environment    string // name of the deployment environment
+	TimeoutMinutes string
+	Strategy       *Strategy
+	Concurrency    *Concurrency
+	Permissions    *Permissions
+	Outputs        map[string]string // expressions by output name
+	Uses           string            // reusable workflow called by the job, if any
+	With           map[string]any    // inputs of the called reusable workflow
+	Secrets        *Secrets          // secrets of the called reusable workflow
+	Steps          []Step
+}
+
+// Strategy is the 'strategy' of a job.
+type Strategy struct {
+	Matrix      *Matrix
+	FailFast    string
+	MaxParallel string
+}
+
+// Matrix is the 'strategy.matrix' of a job: an expression, or a set of dimensions with optional include
+// and exclude entries.
+type Matrix struct {
+	Expression     string
+	Dimensions     map[string][]any
+	DimensionOrder []string // dimension names in document order
+	Include        []map[string]any
+	Exclude        []map[string]any
+}
+
+// Concurrency is the 'concurrency' of a job.
+type Concurrency struct {
+	Group            string
+	CancelInProgress string
+}
+
+// Permissions are the 'permissions' of a job: read-all or write-all, or the access of each scope.
+type Permissions struct {
+	All    string
+	Scopes map[string]string
+}
+
+// Secrets are the 'secrets' passed to a reusable workflow: all the secrets of the caller, or the
+// expressions of each secret.
+type Secrets struct {
+	Inherit bool
+	Values  map[string]string
+}
+
+// Step is a step of a job or of a composite action.
+type Step struct {
+	ID   string
+	Name string
+	Uses string
+	Run  string
+	If   string
+}
+
+// Action is a parsed action metadata file (action.yml).
+type Action struct {
+	Name        string
+	Description string
+	Using       string // runs.using: composite, docker, node20, ...
+	Steps       []Step // steps of a composite action
+}
+
+// Types of 'uses' references.
+const (
+	UsesTypeLocal  = github.UsesTypeLocal  // ./path in the repository of the workflow
+	UsesTypeRemote = github.UsesTypeRemote // owner/repo[/path]@ref
+	UsesTypeDocker = github.UsesTypeDocker // docker://image
+)
+
+// Targets of 'uses' references.
+const (
+	UsesTargetAction   = github.UsesTargetAction
+	UsesTargetWorkflow = github.UsesTargetWorkflow
+)
+
+// ActionRef is a parsed 'uses' reference (see ParseActionRef).
+type ActionRef struct {
+	Type   string // one of the UsesType constants
+	Target string // one of the UsesTarget constants
+	Owner  string
+	Repo   string
+	Ref    string
+	Path   string // path in the repository, or the image of docker references
+	Raw    string // original uses string
+	SHA    string // commit SHA the ref resolved to, when known
+}
+
+// IsWorkflow reports whether the reference points at a reusable workflow rather than an action.
+func (ar ActionRef) IsWorkflow() bool {
+	return ar.Target == UsesTargetWorkflow
+}
+
+// Reasons why a 'uses' reference could not be resolved.
+const (
+	UnresolvedNotFound   = github.UnresolvedNotFound
+	UnresolvedAuth       = github.UnresolvedAuth
+	UnresolvedRateLimit  = github.UnresolvedRateLimit
+	UnresolvedParse      = github.UnresolvedParse
+	UnresolvedInvalid    = github.UnresolvedInvalid
+	UnresolvedDepthLimit = github.UnresolvedDepthLimit
+	UnresolvedError      = github.UnresolvedError
+)
+
+// ResolveError describes why a 'uses' reference could not be resolved.
+type ResolveError struct {
+	Uses   string
+	Reason string // one of the Unresolved constants
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *ResolveError) Error() string {
+	return (&github.ResolveError{Uses: e.Uses, Reason: e.Reason, Err: e.Err}).Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// Problem is a reference of the tree that could not be resolved.
+type Problem struct {
+	NodeID string
+	Uses   string
+	Reason string // one of the Unresolved constants
+	Err    error
+}
+
+// Cycle is a chain of references of the tree leading back to a workflow or action being expanded.
+type Cycle struct {
+	NodeID string   // ID of the node closing the cycle
+	Uses   []string // references of the cycle, starting and ending with the same document
+}
+
+// String returns the references of the cycle joined by arrows.
+func (c Cycle) String() string {
+	return strings.Join(c.Uses, " -> ")
+}
+
+// Kinds of the nodes of the dependency tree.
+const (
+	NodeKindRepository = github.NodeKindRepository
+	NodeKindWorkflow   = github.NodeKindWorkflow
+	NodeKindJob        = github.NodeKindJob
+	NodeKindAction     = github.NodeKindAction
+	NodeKindStep       = github.NodeKindStep
+)
+
+// Node is a node of the dependency tree: a repository, workflow, job, action or step.
+type Node struct {
+	node     *github.UsesNode
+	children []*Node
+}
+
+// ID returns the identifier of the node, unique in the tree.
+func (n *Node) ID() string { return n.node.UniqueID }
+
+// Name returns the name of the node: the workflow or job name, or the 'uses' reference of a step.
+func (n *Node) Name() string { return n.node.Name }
+
+// Title returns the name of the workflow or action the node was resolved from, if it has one.
+func (n *Node) Title() string { return n.node.Title }
+
+// Kind returns the kind of the node, one of the NodeKind constants.
+func (n *Node) Kind() string { return n.node.Kind }
+
+// Uses returns the 'uses' reference of the node, if any.
+func (n *Node) Uses() string { return n.node.Uses }
+
+// URL returns the source URL of the workflow or action the node was resolved from, if any.
+func (n *Node) URL() string { return n.node.URL }
+
+// Ref returns the reference the node was resolved from, or nil.
+func (n *Node) Ref() *ActionRef { return newActionRef(n.node.Ref) }
+
+// Needs returns the IDs of the sibling jobs the node depends on.
+func (n *Node) Needs() []string { return append([]string(nil), n.node.Needs...) }
+
+// Children returns the jobs, steps or referenced workflows and actions under the node.
+func (n *Node) Children() []*Node { return append([]*Node(nil), n.children...) }
+
+// Cycle returns the references of the cycle closed by the node, which is not expanded again, or nil.
+func (n *Node) Cycle() []string { return append([]string(nil), n.node.Cycle...) }
+
+// Unresolved returns why the 'uses' reference of the node could not be resolved, or nil.
+func (n *Node) Unresolved() *ResolveError {
+	if n.node.Unresolved == nil {
+		return nil
+	}
+	return &ResolveError{Uses: n.node.Unresolved.Uses, Reason: n.node.Unresolved.Reason, Err: n.node.Unresolved.Err}
+}
+
+// newNode wraps the dependency tree under un.
+func newNode(un *github.UsesNode) *Node {
+	if un == nil {
+		return nil
+	}
+	n := &Node{node: un}
+	for _, child := range un.Children {
+		n.children = append(n.children, newNode(child))
+	}
+	return n
+}
+
+// tree returns the dependency tree under n.
+func (n *Node) tree() *github.UsesNode {
+	if n == nil {
+		return nil
+	}
+	return n.node
+}
+
+// newWorkflow returns the public model of wf.
+func newWorkflow(wf *github.Workflow) *Workflow {
+	if wf == nil {
+		return nil
+	}
+	w := &Workflow{Name: wf.Name, URL: wf.URL, Ref: newActionRef(wf.Ref)}
+	for _, trigger := range wf.On {
+		w.Triggers = append(w.Triggers, trigger.Event)
+	}
+	for _, id := range wf.JobNames() {
+		job := wf.Jobs[id]
+		j := Job{
+			ID:             id,
+			Name:           job.Name,
+			Needs:          append([]string(nil), job.Needs...),
+			RunsOn:         job.RunsOn.String(),
+			If:             job.If,
+			TimeoutMinutes: job.TimeoutMinutes,
+			Outputs:        maps.Clone(job.Outputs),
+			Uses:           job.Uses,
+			With:           maps.Clone(job.With),
+			Steps:          newSteps(job.Steps),
+		}
+		if job.Environment != nil {
+			j.Environment = job.Environment.Name
+		}
+		if s := job.Strategy; s != nil {
+			j.Strategy = &Strategy{Matrix: newMatrix(s.Matrix), FailFast: s.FailFast, MaxParallel: s.MaxParallel}
+		}
+		if c := job.Concurrency; c != nil {
+			j.Concurrency = &Concurrency{Group: c.Group, CancelInProgress: c.CancelInProgress}
+		}
+		if p := job.Permissions; p != nil {
+			j.Permissions = &Permissions{All: p.All, Scopes: maps.Clone(p.Scopes)}
+		}
+		if s := job.Secrets; s != nil {
+			j.Secrets = &Secrets{Inherit: s.Inherit, Values: maps.Clone(s.Values)}
+		}
+		w.Jobs = append(w.Jobs, j)
+	}
+	if a := wf.Action; a != nil {
+		w.Action = &Action{Name: a.Name, Description: a.Description, Using: a.Runs.Using, Steps: newSteps(a.Runs.Steps)}
+	}
+	return w
+}
+
+// newMatrix returns the public model of m, or nil.
+func newMatrix(m *github.Matrix) *Matrix {
+	if m == nil {
+		return nil
+	}
+	matrix := &Matrix{Expression: m.Expression, DimensionOrder: append([]string(nil), m.DimensionOrder...),
+		Include: append([]map[string]any(nil), m.Include...), Exclude: append([]map[string]any(nil), m.Exclude...)}
+	if m.Dimensions != nil {
+		matrix.Dimensions = make(map[string][]any, len(m.Dimensions))
+		for name, values := range m.Dimensions {
+			matrix.Dimensions[name] = append([]any(nil), values...)
+		}
+	}
+	return matrix
+}
+
+// newSteps returns the public model of steps.
+func newSteps(steps []github.Step) []Step {
+	var s []Step
+	for _, step := range steps {
+		s = append(s, Step{ID: step.ID, Name: step.Name, Uses: step.Uses, Run: step.Run, If: step.If})
+	}
+	return s
+}
+
+// newActionRef returns the public model of ar, or nil.
+func newActionRef(ar *github.ActionRef) *ActionRef {
+	if ar == nil {
+		return nil
+	}
+	target := UsesTargetAction
+	if ar.IsWorkflow() {
+		target = UsesTargetWorkflow
+	}
+	return &ActionRef{Type: ar.Type, Target: target, Owner: ar.Owner, Repo: ar.Repo, Ref: ar.Ref, Path: ar.Path, Raw: ar.Raw, SHA: ar.SHA}
+}
+
+// publicError returns err with the *github.ResolveError it wraps, if any, converted to a *ResolveError.
+func publicError(err error) error {
+	var resolveErr *github.ResolveError
+	if !errors.As(err, &resolveErr) {
+		return err
+	}
+	return &ResolveError{Uses: resolveErr.Uses, Reason: resolveErr.Reason, Err: resolveErr.Err}
+}
@@ -0,0 +1,134 @@
+package wk2mmd
+
+import (
+	"github.com/leocomelli/wk2mmd/internal/github"
+)
+
+// DefaultDepth is the default maximum depth of the 'uses' references followed by Analyze.
+const DefaultDepth = 2
+
+// Option configures Analyze.
+type Option func(*config)
+
+type config struct {
+	client      WorkflowDownloader
+	token       string
+	host        string
+	depth       int
+	concurrency int
+}
+
+// WithDownloader sets the downloader used to fetch the workflows and the referenced actions, e.g. a
+// client wrapped with a cache, or an in-memory implementation. It takes precedence over WithToken and WithHost.
+func WithDownloader(downloader WorkflowDownloader) Option {
+	return func(c *config) {
+		c.client = downloader
+	}
+}
+
+// WithToken sets the GitHub token of the default client.
+func WithToken(token string) Option {
+	return func(c *config) {
+		c.token = token
+	}
+}
+
+// WithHost sets the GitHub Enterprise Server host of the default client (github.com by default).
+func WithHost(host string) Option {
+	return func(c *config) {
+		c.host = host
+	}
+}
+
+// WithDepth sets the maximum depth of the 'uses' references that are followed (DefaultDepth by default).
+func WithDepth(depth int) Option {
+	return func(c *config) {
+		c.depth = depth
+	}
+}
+
+// WithConcurrency sets how many references are fetched in parallel.
+func WithConcurrency(concurrency int) Option {
+	return func(c *config) {
+		c.concurrency = concurrency
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{depth: DefaultDepth, concurrency: github.DefaultConcurrency}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// downloader returns the configured downloader, or a GitHub client for the configured token and host.
+func (c *config) downloader() WorkflowDownloader {
+	if c.client != nil {
+		return downloaderOf(c.client)
+	}
+	return downloaderOf(NewClient(c.token, c.host))
+}
+
+// RenderOption configures Render.
+type RenderOption func(*renderConfig)
+
+type renderConfig struct {
+	format      string
+	diagramType string
+	view        string
+	detail      string
+	theme       *Theme
+	legend      bool
+}
+
+// WithFormat sets the output format (FormatMermaid by default).
+func WithFormat(format string) RenderOption {
+	return func(c *renderConfig) {
+		c.format = format
+	}
+}
+
+// WithDiagramType sets the Mermaid diagram type (DiagramFlowchart by default). Other formats only
+// support flowcharts.
+func WithDiagramType(diagramType string) RenderOption {
+	return func(c *renderConfig) {
+		c.diagramType = diagramType
+	}
+}
+
+// WithView sets the view of the dependency graph (ViewTree by default).
+func WithView(view string) RenderOption {
+	return func(c *renderConfig) {
+		c.view = view
+	}
+}
+
+// WithDetail sets the detail level of the dependency graph (DetailJobs by default).
+func WithDetail(detail string) RenderOption {
+	return func(c *renderConfig) {
+		c.detail = detail
+	}
+}
+
+// WithTheme sets the theme of the Mermaid flowcharts (DefaultTheme by default, see LoadTheme).
+func WithTheme(theme *Theme) RenderOption {
+	return func(c *renderConfig) {
+		c.theme = theme
+	}
+}
+
+// WithLegend adds a legend of the kinds of nodes to the Mermaid flowcharts.
+func WithLegend(legend bool) RenderOption {
+	return func(c *renderConfig) {
+		c.legend = legend
+	}
+}
+
+func newRenderConfig(opts []RenderOption) *renderConfig {
+	c := &renderConfig{format: FormatMermaid, diagramType: DiagramFlowchart, view: ViewTree, detail: DetailJobs}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
@@ -0,0 +1,52 @@
+package wk2mmd
+
+import (
+	"maps"
+
+	"github.com/leocomelli/wk2mmd/internal/diagram"
+)
+
+// Theme is the look of the Mermaid flowcharts: the Mermaid theme and options, and the style of each kind
+// of node. It has the format of the theme files (see LoadTheme).
+type Theme struct {
+	Mermaid MermaidOptions   `yaml:"mermaid"`
+	Styles  map[string]Style `yaml:"styles"` // by kind of node, e.g. job or thirdPartyAction
+}
+
+// MermaidOptions are the diagram wide options of a Theme.
+type MermaidOptions struct {
+	Theme string         `yaml:"theme"` // Mermaid theme: default, neutral, dark, forest or base
+	Init  map[string]any `yaml:"init"`  // options of the %%{init}%% directive, e.g. themeVariables
+}
+
+// Style is the shape and colors of a kind of node of a Theme. Empty fields are not set.
+type Style struct {
+	Shape       string `yaml:"shape"` // Mermaid shape name, e.g. rect, rounded, hex or cyl
+	Fill        string `yaml:"fill"`
+	Stroke      string `yaml:"stroke"`
+	Color       string `yaml:"color"`
+	StrokeWidth int    `yaml:"stroke-width"`
+	StrokeDash  string `yaml:"stroke-dasharray"`
+}
+
+// newTheme returns the public model of t.
+func newTheme(t *diagram.Theme) *Theme {
+	theme := &Theme{Mermaid: MermaidOptions{Theme: t.Mermaid.Theme, Init: t.Mermaid.Init}, Styles: make(map[string]Style, len(t.Styles))}
+	for kind, style := range t.Styles {
+		theme.Styles[kind] = Style(style)
+	}
+	return theme
+}
+
+// theme returns the diagram theme of t, or nil.
+func (t *Theme) theme() *diagram.Theme {
+	if t == nil {
+		return nil
+	}
+	theme := &diagram.Theme{Mermaid: diagram.MermaidOptions{Theme: t.Mermaid.Theme, Init: maps.Clone(t.Mermaid.Init)},
+		Styles: make(map[string]diagram.Style, len(t.Styles))}
+	for kind, style := range t.Styles {
+		theme.Styles[kind] = diagram.Style(style)
+	}
+	return theme
+}
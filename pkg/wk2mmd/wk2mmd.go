@@ -0,0 +1,234 @@
+// Package wk2mmd is the public API of wk2mmd: it parses GitHub Actions workflows, resolves the reusable
+// workflows and actions they reference, and renders the resulting dependency graph as Mermaid, Graphviz DOT,
+// PlantUML or D2 diagrams, or exports it as JSON or YAML.
+//
+//	analysis, err := wk2mmd.Analyze(ctx, "https://github.com/owner/repo/blob/main/.github/workflows/ci.yml",
+//		wk2mmd.WithToken(os.Getenv("GITHUB_TOKEN")), wk2mmd.WithDepth(3))
+//	if err != nil {
+//		return err
+//	}
+//	diagram, err := analysis.Render(wk2mmd.WithFormat(wk2mmd.FormatDOT))
+package wk2mmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leocomelli/wk2mmd/internal/app"
+	"github.com/leocomelli/wk2mmd/internal/diagram"
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/leocomelli/wk2mmd/internal/graph"
+)
+
+// WorkflowDownloader downloads workflow and action files from a URL or a local path. Implementations may
+// also list the workflows of remote repositories, with a ListWorkflows method like the one of Client.
+// References are resolved to commit SHAs with a Client only.
+type WorkflowDownloader interface {
+	DownloadWorkflow(ctx context.Context, url string) ([]byte, error)
+}
+
+// Client is the GitHub implementation of WorkflowDownloader: it reads files from github.com or a GitHub
+// Enterprise Server host, lists the workflows of remote repositories and resolves references to commit
+// SHAs through the contents and git refs APIs.
+type Client struct {
+	client *github.Client
+}
+
+// SetTimeout sets the timeout of each HTTP request (0 means no timeout) and returns the client for chaining.
+func (c *Client) SetTimeout(timeout time.Duration) *Client {
+	c.client.SetTimeout(timeout)
+	return c
+}
+
+// SetMaxRetries sets how many times a failed request is retried and returns the client for chaining.
+func (c *Client) SetMaxRetries(retries int) *Client {
+	c.client.SetMaxRetries(retries)
+	return c
+}
+
+// GitHubHost returns the host the client is bound to.
+func (c *Client) GitHubHost() string {
+	return c.client.GitHubHost()
+}
+
+// DownloadWorkflow downloads a workflow or action file from a URL of the client host or a local path.
+func (c *Client) DownloadWorkflow(ctx context.Context, url string) ([]byte, error) {
+	return c.client.DownloadWorkflow(ctx, url)
+}
+
+// ListWorkflows returns the paths of the workflow files of owner/repo at ref.
+func (c *Client) ListWorkflows(ctx context.Context, owner, repo, ref string) ([]string, error) {
+	return c.client.ListWorkflows(ctx, owner, repo, ref)
+}
+
+// downloaderOf returns the downloader used to resolve references: the GitHub client of a Client, so that
+// its optional APIs are used, or d itself.
+func downloaderOf(d WorkflowDownloader) WorkflowDownloader {
+	if c, ok := d.(*Client); ok {
+		return c.client
+	}
+	return d
+}
+
+// Output formats.
+const (
+	FormatMermaid  = diagram.FormatMermaid
+	FormatDOT      = diagram.FormatDOT
+	FormatPlantUML = diagram.FormatPlantUML
+	FormatD2       = diagram.FormatD2
+	FormatJSON     = diagram.FormatJSON
+	FormatYAML     = diagram.FormatYAML
+)
+
+// Views of the dependency graph.
+const (
+	// ViewTree lists every workflow and action under each of its callers.
+	ViewTree = graph.ViewTree
+	// ViewDAG has one node per unique reference, shared by all its callers.
+	ViewDAG = graph.ViewDAG
+)
+
+// Detail levels of the dependency graph.
+const (
+	// DetailJobs lists the jobs, and the steps that use an action.
+	DetailJobs = graph.DetailJobs
+	// DetailSteps lists every step of the jobs and composite actions, in order.
+	DetailSteps = graph.DetailSteps
+	// DetailFull lists every step, and the diagrams show the attributes of the jobs and steps.
+	DetailFull = graph.DetailFull
+)
+
+// Built-in themes.
+const (
+	ThemeDefault = diagram.ThemeDefault
+	ThemeDark    = diagram.ThemeDark
+)
+
+// Mermaid diagram types.
+const (
+	DiagramFlowchart = "flowchart"
+	DiagramSequence  = "sequence"
+)
+
+// ParseWorkflow parses the YAML of a workflow downloaded from url.
+func ParseWorkflow(url string, data []byte) (*Workflow, error) {
+	wf, err := github.ParseWorkflowYAML(url, data)
+	if err != nil {
+		return nil, err
+	}
+	return newWorkflow(wf), nil
+}
+
+// LoadTheme returns a built-in theme (ThemeDefault or ThemeDark), or reads a YAML theme file. The styles a
+// theme file does not set are taken from the default theme.
+func LoadTheme(nameOrPath string) (*Theme, error) {
+	theme, err := diagram.LoadTheme(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+	return newTheme(theme), nil
+}
+
+// ParseActionRef parses and classifies a 'uses' reference: a local action or reusable workflow, a remote
+// action or reusable workflow, or a docker image. The error tells what is wrong with invalid references.
+func ParseActionRef(uses string) (ActionRef, error) {
+	ar, err := github.ParseActionRef(uses, github.Repository{})
+	if err != nil {
+		return ActionRef{}, err
+	}
+	return *newActionRef(&ar), nil
+}
+
+// NewClient returns a GitHub client for the given host (github.com when empty), authenticated with token
+// when it is not empty.
+func NewClient(token, host string) *Client {
+	return &Client{client: github.NewClientForHost(token, host)}
+}
+
+// Resolve downloads and parses the reusable workflow or action referenced by uses.
+// The error is a *ResolveError telling why the reference could not be resolved; docker:// references
+// have no workflow or action metadata and are invalid references.
+func Resolve(ctx context.Context, downloader WorkflowDownloader, uses string) (*Workflow, error) {
+	ar, err := github.ParseActionRef(uses, github.Repository{})
+	if err != nil {
+		return nil, &ResolveError{Uses: uses, Reason: UnresolvedInvalid, Err: err}
+	}
+	if ar.Type == github.UsesTypeDocker {
+		return nil, &ResolveError{Uses: uses, Reason: UnresolvedInvalid, Err: errDockerImage}
+	}
+	wf, err := github.FetchActionWorkflow(ctx, downloaderOf(downloader), ar)
+	if err != nil {
+		return nil, publicError(err)
+	}
+	return newWorkflow(wf), nil
+}
+
+// errDockerImage is the error of the docker:// references passed to Resolve.
+var errDockerImage = errors.New("docker images have no workflow or action metadata")
+
+// Analysis is the result of Analyze.
+type Analysis struct {
+	// Root is the dependency tree: the analyzed workflow, or a repository node whose children are
+	// the workflows of the repository.
+	Root *Node
+	// Problems lists the references that could not be resolved.
+	Problems []Problem
+	// Cycles lists the reference cycles, each closed by a reference that was not expanded again.
+	Cycles []Cycle
+}
+
+// Analyze builds the dependency tree of a workflow (a URL or a local file) or, when source is a local
+// directory or an owner/repo[@ref] reference, of every workflow of the repository.
+// References that cannot be resolved or form cycles do not fail the analysis; they are reported in
+// Analysis.Problems and Analysis.Cycles.
+func Analyze(ctx context.Context, source string, opts ...Option) (*Analysis, error) {
+	cfg := newConfig(opts)
+	runner := app.NewWorkflowRunnerWithClient(cfg.downloader()).SetConcurrency(cfg.concurrency)
+
+	var root *github.UsesNode
+	var err error
+	if app.IsRepositorySource(source) {
+		root, err = runner.AnalyzeRepository(ctx, source, cfg.depth)
+	} else {
+		root, err = runner.AnalyzeWorkflow(ctx, source, cfg.depth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	analysis := &Analysis{Root: newNode(root)}
+	for _, p := range github.Problems(root) {
+		analysis.Problems = append(analysis.Problems, Problem{NodeID: p.NodeID, Uses: p.Uses, Reason: p.Reason, Err: p.Err})
+	}
+	for _, c := range github.Cycles(root) {
+		analysis.Cycles = append(analysis.Cycles, Cycle{NodeID: c.NodeID, Uses: c.Uses})
+	}
+	return analysis, nil
+}
+
+// Graph returns the machine-readable dependency graph of the analysis, in the tree view.
+func (a *Analysis) Graph() *Graph {
+	return newGraph(graph.FromTree(a.Root.tree()))
+}
+
+// DAG returns the machine-readable dependency graph of the analysis, with one node per unique reference.
+func (a *Analysis) DAG() *Graph {
+	return newGraph(graph.FromDAG(a.Root.tree()))
+}
+
+// Render renders the dependency tree of the analysis, as a Mermaid flowchart by default.
+func (a *Analysis) Render(opts ...RenderOption) (string, error) {
+	return Render(a.Root, opts...)
+}
+
+// Render renders a dependency tree, as a Mermaid flowchart by default.
+func Render(root *Node, opts ...RenderOption) (string, error) {
+	cfg := newRenderConfig(opts)
+	renderer, err := diagram.NewRenderer(cfg.format, cfg.diagramType, diagram.WithView(cfg.view), diagram.WithDetail(cfg.detail),
+		diagram.WithTheme(cfg.theme.theme()), diagram.WithLegend(cfg.legend))
+	if err != nil {
+		return "", fmt.Errorf("failed to create renderer: %w", err)
+	}
+	return renderer.Render(root.tree())
+}
@@ -0,0 +1,213 @@
+package wk2mmd
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leocomelli/wk2mmd/internal/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// memDownloader serves workflow and action files from memory.
+type memDownloader map[string]string
+
+func (m memDownloader) DownloadWorkflow(ctx context.Context, url string) ([]byte, error) {
+	if data, ok := m[url]; ok {
+		return []byte(data), nil
+	}
+	return nil, &github.StatusError{StatusCode: http.StatusNotFound}
+}
+
+var files = memDownloader{
+	"ci.yml": `
+on: push
+jobs:
+  build:
+    steps:
+      - uses: ./actions/setup
+  deploy:
+    needs: build
+    uses: ./.github/workflows/deploy.yml
+`,
+	"./actions/setup/action.yml":     "name: Setup\nruns:\n  using: node20\n  main: index.js\n",
+	"./.github/workflows/deploy.yml": "jobs:\n  release:\n    uses: ./.github/workflows/missing.yml\n",
+}
+
+func TestAnalyze(t *testing.T) {
+	analysis, err := Analyze(context.Background(), "ci.yml", WithDownloader(files), WithDepth(3), WithConcurrency(2))
+	assert.NoError(t, err)
+	assert.Equal(t, "workflow", analysis.Root.Name())
+	assert.Equal(t, NodeKindWorkflow, analysis.Root.Kind())
+	assert.Len(t, analysis.Root.Children(), 2)
+	assert.Equal(t, []Problem{{
+		NodeID: "workflow/deploy/release",
+		Uses:   "./.github/workflows/missing.yml",
+		Reason: UnresolvedNotFound,
+		Err:    analysis.Problems[0].Err,
+	}}, analysis.Problems)
+
+	deploy := analysis.Root.Children()[1]
+	assert.Equal(t, "workflow/deploy", deploy.ID())
+	assert.Equal(t, []string{"workflow/build"}, deploy.Needs())
+	assert.True(t, deploy.Ref().IsWorkflow())
+	release := deploy.Children()[0]
+	assert.Equal(t, "./.github/workflows/missing.yml", release.Uses())
+	assert.Equal(t, UnresolvedNotFound, release.Unresolved().Reason)
+	assert.Nil(t, deploy.Unresolved())
+	assert.Equal(t, "Setup", analysis.Root.Children()[0].Children()[0].Title())
+
+	out, err := analysis.Render()
+	assert.NoError(t, err)
+	assert.Contains(t, out, "flowchart")
+
+	out, err = analysis.Render(WithFormat(FormatDOT))
+	assert.NoError(t, err)
+	assert.Contains(t, out, "digraph workflow")
+
+	_, err = analysis.Render(WithFormat(FormatDOT), WithDiagramType(DiagramSequence))
+	assert.Error(t, err)
+
+	g := analysis.Graph()
+	assert.Equal(t, "workflow", g.Nodes[0].ID)
+
+	out, err = analysis.Render(WithFormat(FormatJSON), WithView(ViewDAG))
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"id": "./.github/workflows/deploy.yml"`)
+	assert.Len(t, analysis.DAG().Nodes, len(g.Nodes)+2, "each called workflow has its own node")
+
+	out, err = analysis.Render(WithFormat(FormatJSON), WithDetail(DetailSteps))
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"type": "step"`)
+
+	theme, err := LoadTheme(ThemeDark)
+	assert.NoError(t, err)
+	out, err = analysis.Render(WithTheme(theme), WithLegend(true))
+	assert.NoError(t, err)
+	assert.Contains(t, out, "theme: dark")
+	assert.Contains(t, out, "subgraph legend [Legend]")
+}
+
+func TestAnalyze_DefaultDepth(t *testing.T) {
+	analysis, err := Analyze(context.Background(), "ci.yml", WithDownloader(files))
+	assert.NoError(t, err)
+	assert.Empty(t, analysis.Problems, "the missing workflow is beyond the default depth")
+	assert.Empty(t, analysis.Cycles)
+
+	_, err = Analyze(context.Background(), "missing.yml", WithDownloader(files))
+	assert.ErrorContains(t, err, "failed to download workflow")
+}
+
+func TestResolve(t *testing.T) {
+	wf, err := Resolve(context.Background(), files, "./actions/setup")
+	assert.NoError(t, err)
+	assert.Equal(t, "Setup", wf.Name)
+	assert.Equal(t, "node20", wf.Action.Using)
+	assert.Equal(t, "./actions/setup", wf.Ref.Raw)
+
+	_, err = Resolve(context.Background(), files, "./actions/missing")
+	var resolveErr *ResolveError
+	assert.ErrorAs(t, err, &resolveErr)
+	assert.Equal(t, UnresolvedNotFound, resolveErr.Reason)
+
+	_, err = Resolve(context.Background(), files, "actions/checkout")
+	assert.ErrorAs(t, err, &resolveErr)
+	assert.Equal(t, UnresolvedInvalid, resolveErr.Reason)
+
+	wf, err = Resolve(context.Background(), files, "docker://alpine:3")
+	assert.Nil(t, wf)
+	assert.ErrorAs(t, err, &resolveErr)
+	assert.Equal(t, UnresolvedInvalid, resolveErr.Reason)
+
+	// Docker steps are leaves of the analysis.
+	docker := memDownloader{"ci.yml": "jobs:\n  build:\n    steps:\n      - uses: docker://alpine:3\n"}
+	analysis, err := Analyze(context.Background(), "ci.yml", WithDownloader(docker), WithDepth(3))
+	assert.NoError(t, err)
+	assert.Empty(t, analysis.Problems)
+}
+
+func TestParseWorkflow(t *testing.T) {
+	wf, err := ParseWorkflow("ci.yml", []byte(files["ci.yml"]))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"push"}, wf.Triggers)
+	assert.Len(t, wf.Jobs, 2)
+	assert.Equal(t, "deploy", wf.Jobs[1].ID)
+	assert.Equal(t, []string{"build"}, wf.Jobs[1].Needs)
+	assert.Equal(t, "./actions/setup", wf.Jobs[0].Steps[0].Uses)
+
+	wf, err = ParseWorkflow("release.yml", []byte(`
+jobs:
+  build:
+    timeout-minutes: 30
+    environment: production
+    concurrency: {group: build, cancel-in-progress: true}
+    permissions: {contents: read}
+    strategy: {fail-fast: false, max-parallel: 2, matrix: {os: [ubuntu-latest, windows-latest]}}
+    outputs: {version: "${{ steps.version.outputs.value }}"}
+  release:
+    uses: ./.github/workflows/publish.yml
+    with: {dry-run: true}
+    secrets: inherit
+`))
+	assert.NoError(t, err)
+	build, release := wf.Jobs[0], wf.Jobs[1]
+	assert.Equal(t, "30", build.TimeoutMinutes)
+	assert.Equal(t, "production", build.Environment)
+	assert.Equal(t, &Concurrency{Group: "build", CancelInProgress: "true"}, build.Concurrency)
+	assert.Equal(t, &Permissions{Scopes: map[string]string{"contents": "read"}}, build.Permissions)
+	assert.Equal(t, &Strategy{FailFast: "false", MaxParallel: "2", Matrix: &Matrix{
+		Dimensions: map[string][]any{"os": {"ubuntu-latest", "windows-latest"}}, DimensionOrder: []string{"os"}}}, build.Strategy)
+	assert.Equal(t, map[string]string{"version": "${{ steps.version.outputs.value }}"}, build.Outputs)
+	assert.Equal(t, map[string]any{"dry-run": true}, release.With)
+	assert.Equal(t, &Secrets{Inherit: true}, release.Secrets)
+
+	ar, err := ParseActionRef("owner/repo/.github/workflows/ci.yml@v1")
+	assert.NoError(t, err)
+	assert.True(t, ar.IsWorkflow())
+	assert.Equal(t, UsesTypeRemote, ar.Type)
+	assert.Equal(t, "owner", ar.Owner)
+	assert.Equal(t, "v1", ar.Ref)
+
+	_, err = ParseActionRef("actions/checkout")
+	assert.ErrorContains(t, err, "missing @ref")
+}
+
+func TestNewClient(t *testing.T) {
+	client := NewClient("token", "ghes.example.com")
+	assert.Equal(t, "ghes.example.com", client.GitHubHost())
+	assert.Same(t, client, client.SetTimeout(time.Second).SetMaxRetries(1))
+	assert.IsType(t, &github.Client{}, newConfig([]Option{WithDownloader(client)}).downloader())
+
+	cfg := newConfig([]Option{WithToken("token"), WithHost("ghes.example.com")})
+	assert.Equal(t, "ghes.example.com", github.HostOf(cfg.downloader()))
+	assert.Equal(t, DefaultDepth, cfg.depth)
+}
+
+func TestAnalysis_GraphAndTheme(t *testing.T) {
+	analysis, err := Analyze(context.Background(), "ci.yml", WithDownloader(files), WithDepth(3))
+	assert.NoError(t, err)
+
+	// The public graph is encoded like the JSON and YAML outputs.
+	for format, encode := range map[string]func(*Graph) (string, error){FormatJSON: (*Graph).JSON, FormatYAML: (*Graph).YAML} {
+		want, err := analysis.Render(WithFormat(format), WithView(ViewDAG))
+		assert.NoError(t, err)
+		got, err := encode(analysis.DAG())
+		assert.NoError(t, err)
+		assert.Equal(t, strings.TrimSpace(want), strings.TrimSpace(got), format)
+	}
+
+	theme, err := LoadTheme(ThemeDark)
+	assert.NoError(t, err)
+	assert.Equal(t, "dark", theme.Mermaid.Theme)
+	theme.Styles["job"] = Style{Shape: "hex", Fill: "#123456"}
+	out, err := analysis.Render(WithTheme(theme))
+	assert.NoError(t, err)
+	assert.Contains(t, out, "fill:#123456")
+
+	// The built-in themes are not changed through the returned copies.
+	theme, err = LoadTheme(ThemeDark)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "#123456", theme.Styles["job"].Fill)
+}